@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ---------- ACCOUNTS ----------
+
+// Accounts are optional: sessions created while logged out have no owner and
+// stay readable by anyone with the ID, as before.
+
+const (
+	loginCookieName   = "shadow_login"
+	loginTTL          = 30 * 24 * time.Hour
+	magicLinkTTL      = 15 * time.Minute
+	passwordCost      = 12 // bcrypt cost; about a quarter of a second per check
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+
+	// After this many wrong passwords for a username from one client address within
+	// failedLoginWindow, further attempts from there are refused without checking, so
+	// guessing can't keep the CPU busy. Other addresses, e.g. the owner's, are unaffected.
+	maxFailedLogins   = 5
+	failedLoginWindow = 15 * time.Minute
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+// A local account
+type User struct {
+	Username     string
+	Email        string
+	PasswordHash []byte // bcrypt hash; empty for magic-link-only accounts
+	CreatedAt    time.Time
+}
+
+// Recent wrong passwords for one username from one client address
+type loginFailures struct {
+	Count int
+	Since time.Time
+}
+
+// A pending or active login token
+type loginToken struct {
+	Username string
+	Expires  time.Time
+}
+
+// Account store (in-memory, like sessions)
+var (
+	users        = make(map[string]*User)
+	logins       = make(map[string]loginToken)    // login cookie value -> user
+	magicLinks   = make(map[string]loginToken)    // one-time magic link token -> user
+	failures     = make(map[string]loginFailures) // failureKey -> wrong passwords
+	usersMutex   sync.Mutex
+	errBadLogin  = fmt.Errorf("invalid username or password")
+	errLockedOut = fmt.Errorf("too many failed logins; try again in %d minutes", int(failedLoginWindow.Minutes()))
+)
+
+// Mailer delivers magic-link emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer "sends" mail by writing it to the server log; swap it for a real
+// Mailer in production.
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("[MAIL] to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+var mailer Mailer = LogMailer{}
+
+// siteURL is where magic links point. Set BASE_URL (e.g. https://shadow.example.com)
+// when serving anywhere but localhost. It never comes from the request: a forged
+// Host header would otherwise get a victim's login token sent to another site.
+var siteURL = func() string {
+	if u := strings.TrimRight(os.Getenv("BASE_URL"), "/"); u != "" {
+		return u
+	}
+	return "http://localhost:8080"
+}()
+
+// randomToken returns an unguessable URL-safe token
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CreateUser registers a password account
+func CreateUser(username, password string) (*User, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("usernames are 3-32 letters, digits, '-' or '_'")
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("passwords need at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("passwords can be at most %d bytes", maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return nil, err
+	}
+	u := &User{
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	if _, exists := users[strings.ToLower(username)]; exists {
+		return nil, fmt.Errorf("that username is taken")
+	}
+	users[strings.ToLower(username)] = u
+	return u, nil
+}
+
+// failureKey identifies the username and client address wrong passwords are counted for
+func failureKey(username, client string) string {
+	return strings.ToLower(username) + " " + client
+}
+
+// clientAddr is the address a request came from, without its port
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Authenticate checks a username and password sent from client (see clientAddr).
+// A username with too many recent failures from that client is refused before any hashing.
+func Authenticate(username, password, client string) (*User, error) {
+	key := failureKey(username, client)
+	usersMutex.Lock()
+	u, ok := users[strings.ToLower(username)]
+	f := failures[key]
+	usersMutex.Unlock()
+	if !ok || len(u.PasswordHash) == 0 {
+		return nil, errBadLogin
+	}
+	if f.Count >= maxFailedLogins && time.Since(f.Since) < failedLoginWindow {
+		return nil, errLockedOut
+	}
+
+	err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password))
+
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	if err != nil {
+		f = failures[key]
+		if time.Since(f.Since) >= failedLoginWindow {
+			f = loginFailures{Since: time.Now()}
+		}
+		f.Count++
+		failures[key] = f
+		pruneFailures()
+		return nil, errBadLogin
+	}
+	delete(failures, key)
+	return u, nil
+}
+
+// pruneFailures forgets failures older than failedLoginWindow once many have piled
+// up, so guesses from many addresses can't grow the map forever; callers hold usersMutex
+func pruneFailures() {
+	if len(failures) < 1000 {
+		return
+	}
+	for k, f := range failures {
+		if time.Since(f.Since) >= failedLoginWindow {
+			delete(failures, k)
+		}
+	}
+}
+
+// userForEmail finds the account for an email address, creating a magic-link-only one if needed
+func userForEmail(email string) *User {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	for _, u := range users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+	u := &User{Username: email, Email: email, CreatedAt: time.Now()}
+	users[strings.ToLower(email)] = u
+	return u
+}
+
+// SendMagicLink emails a one-time login link for the given address
+func SendMagicLink(email, baseURL string) error {
+	u := userForEmail(email)
+	token := randomToken()
+	usersMutex.Lock()
+	magicLinks[token] = loginToken{Username: u.Username, Expires: time.Now().Add(magicLinkTTL)}
+	usersMutex.Unlock()
+
+	link := fmt.Sprintf("%s/login/magic?token=%s", baseURL, token)
+	return mailer.Send(email, "Your ShadowReddit login link",
+		fmt.Sprintf("Open this link within %d minutes to log in:\n%s\n", int(magicLinkTTL.Minutes()), link))
+}
+
+// redeemMagicLink consumes a magic link token and returns its user
+func redeemMagicLink(token string) (string, bool) {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	t, ok := magicLinks[token]
+	delete(magicLinks, token)
+	if !ok || time.Now().After(t.Expires) {
+		return "", false
+	}
+	return t.Username, true
+}
+
+// startLogin sets the login cookie for a user
+func startLogin(w http.ResponseWriter, username string) {
+	token := randomToken()
+	usersMutex.Lock()
+	logins[token] = loginToken{Username: username, Expires: time.Now().Add(loginTTL)}
+	usersMutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(loginTTL),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Request context key for the user withCurrentUser resolved
+type currentUserKey struct{}
+
+// withCurrentUser resolves the request's user once, so handlers calling
+// CurrentUser several times don't check a basic auth password each time.
+// Wrong basic auth credentials get a 401: a script must not carry on as
+// anonymous and create sessions anyone with the ID can read.
+func withCurrentUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := resolveUser(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ShadowReddit"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), currentUserKey{}, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CurrentUser returns the logged-in username, from the login cookie or, for
+// scripts using the API, HTTP basic auth. It returns "" for anonymous requests.
+func CurrentUser(r *http.Request) string {
+	if user, ok := r.Context().Value(currentUserKey{}).(string); ok {
+		return user
+	}
+	user, _ := resolveUser(r)
+	return user
+}
+
+// resolveUser finds the request's user. It fails only when basic auth
+// credentials were sent and are wrong or locked out.
+func resolveUser(r *http.Request) (string, error) {
+	if c, err := r.Cookie(loginCookieName); err == nil {
+		usersMutex.Lock()
+		t, ok := logins[c.Value]
+		usersMutex.Unlock()
+		if ok && time.Now().Before(t.Expires) {
+			return t.Username, nil
+		}
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		u, err := Authenticate(username, password, clientAddr(r))
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	}
+	return "", nil
+}
+
+// canView reports whether user may see the session. Owner never changes, so no lock is needed.
+func (s *RedditSession) canView(user string) bool {
+	return s.Owner == "" || s.Owner == user
+}
+
+// authorizeSession looks up ?id= and checks the viewer may see it, writing an
+// error response and returning false otherwise. Other people's sessions look
+// exactly like missing ones so IDs can't be probed.
+func authorizeSession(w http.ResponseWriter, r *http.Request, id string) (*RedditSession, bool) {
+	if id == "" {
+		http.Error(w, "Missing session ID", http.StatusBadRequest)
+		return nil, false
+	}
+	session, ok := GetSession(id)
+	if ok {
+		user := CurrentUser(r)
+		ok = session.canView(user)
+		owned := session.Owner != ""
+
+		if !ok && owned && user == "" && r.Method == "GET" {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(safeNext(r.URL.RequestURI())), http.StatusSeeOther)
+			return nil, false
+		}
+	}
+	if !ok {
+		http.Error(w, "Invalid session ID", http.StatusNotFound)
+		return nil, false
+	}
+	return session, true
+}
+
+// safeNext only allows local redirect targets
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		return "/"
+	}
+	return next
+}
+
+// ---------- ACCOUNT PAGES ----------
+
+// AccountNav shows who is logged in, with login/logout links
+func AccountNav(user string) *Node {
+	if user == "" {
+		return Div(Class("text-sm text-gray-500 space-x-3"),
+			A(Href("/login"), Class("hover:underline"), T("Log in")),
+			A(Href("/signup"), Class("hover:underline"), T("Sign up")),
+		)
+	}
+	return Form(Method("POST"), Action("/logout"), Class("text-sm text-gray-500 space-x-3"),
+		Span(Text("Signed in as "+user)),
+		Button(Type("submit"), Class("hover:underline"), T("Log out")),
+	)
+}
+
+// LoginPage offers password login and magic links
+func LoginPage(next, message string) *Node {
+	return DefaultLayout(
+		Main(Class("max-w-md mx-auto p-8 space-y-6"),
+			H1(Class("text-2xl font-bold"), T("Log in")),
+			If(message != "", P(Class("alert"), Text(message)), Nil()),
+			Form(Method("POST"), Action("/login"), Class("space-y-3"),
+				Input(Type("hidden"), Name("next"), Value(next)),
+				Input(Type("text"), Name("username"), Placeholder("Username"), Class("w-full border rounded p-2")),
+				Input(Type("password"), Name("password"), Placeholder("Password"), Class("w-full border rounded p-2")),
+				Button(Type("submit"), Class("bg-blue-600 text-white px-4 py-2 rounded"), T("Log in")),
+			),
+			Form(Method("POST"), Action("/login/magic"), Class("space-y-3"),
+				P(Class("text-sm text-gray-500"), T("Or get a one-time login link by email:")),
+				Input(Type("email"), Name("email"), Placeholder("you@example.com"), Class("w-full border rounded p-2")),
+				Button(Type("submit"), Class("btn btn-sm"), T("Email me a link")),
+			),
+			P(Class("text-sm"), T("No account? "), A(Href("/signup"), Class("text-blue-600 hover:underline"), T("Sign up"))),
+		),
+	)
+}
+
+// SignupPage creates a password account
+func SignupPage(message string) *Node {
+	return DefaultLayout(
+		Main(Class("max-w-md mx-auto p-8 space-y-6"),
+			H1(Class("text-2xl font-bold"), T("Sign up")),
+			P(Class("text-sm text-gray-500"),
+				T("An account keeps your simulations private to you. You can keep using ShadowReddit anonymously instead."),
+			),
+			If(message != "", P(Class("alert alert-error"), Text(message)), Nil()),
+			Form(Method("POST"), Action("/signup"), Class("space-y-3"),
+				Input(Type("text"), Name("username"), Placeholder("Username"), Class("w-full border rounded p-2")),
+				Input(Type("password"), Name("password"), Placeholder("Password (8+ characters)"), Class("w-full border rounded p-2")),
+				Button(Type("submit"), Class("bg-blue-600 text-white px-4 py-2 rounded"), T("Create account")),
+			),
+		),
+	)
+}
+
+func registerAccountRoutes() {
+	http.HandleFunc("/signup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			ServeNode(SignupPage(""))(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		u, err := CreateUser(strings.TrimSpace(r.FormValue("username")), r.FormValue("password"))
+		if err != nil {
+			ServeNode(SignupPage(err.Error()))(w, r)
+			return
+		}
+		log.Printf("[INFO] Created account %s", u.Username)
+		startLogin(w, u.Username)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	http.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			ServeNode(LoginPage(safeNext(r.URL.Query().Get("next")), ""))(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		next := safeNext(r.FormValue("next"))
+		u, err := Authenticate(strings.TrimSpace(r.FormValue("username")), r.FormValue("password"), clientAddr(r))
+		if err != nil {
+			ServeNode(LoginPage(next, err.Error()))(w, r)
+			return
+		}
+		startLogin(w, u.Username)
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	})
+
+	http.HandleFunc("/login/magic", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			email := strings.TrimSpace(r.FormValue("email"))
+			if !strings.Contains(email, "@") {
+				ServeNode(LoginPage("/", "Enter a valid email address"))(w, r)
+				return
+			}
+			if err := SendMagicLink(email, siteURL); err != nil {
+				log.Printf("[ERROR] sending magic link: %v", err)
+				http.Error(w, "Could not send login link", http.StatusInternalServerError)
+				return
+			}
+			ServeNode(LoginPage("/", "Check your email for a login link."))(w, r)
+			return
+		}
+
+		username, ok := redeemMagicLink(r.URL.Query().Get("token"))
+		if !ok {
+			ServeNode(LoginPage("/", "That login link is invalid or has expired."))(w, r)
+			return
+		}
+		startLogin(w, username)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	http.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if c, err := r.Cookie(loginCookieName); err == nil {
+			usersMutex.Lock()
+			delete(logins, c.Value)
+			usersMutex.Unlock()
+		}
+		http.SetCookie(w, &http.Cookie{Name: loginCookieName, Value: "", Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+}
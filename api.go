@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- JSON API (v1) ----------
+
+// Maximum accepted request body for API calls
+const apiMaxBodyBytes = 1 << 20
+
+// SimulationOptions tweaks how a simulation is generated
+type SimulationOptions struct {
+	Model   string   `json:"model,omitempty"`   // Override the default OpenAI models
+	Stances []Stance `json:"stances,omitempty"` // Skip stance selection; each needs a known type and subtype
+	Seed    *int64   `json:"seed,omitempty"`    // Reproduce an earlier run; random when omitted
+}
+
+// Body of POST /api/v1/simulations
+type apiCreateSimulationRequest struct {
+	Prompt    string            `json:"prompt"`
+	Subreddit string            `json:"subreddit"`
+	Options   SimulationOptions `json:"options"`
+}
+
+// A full session as returned by the API
+type apiSimulation struct {
+	*RedditSession
+	Status string `json:"status"`
+	Done   bool   `json:"done"`            // Whether generation has stopped, whatever the outcome
+	Error  string `json:"error,omitempty"` // Why generation stopped, when it failed
+}
+
+// One row of GET /api/v1/simulations
+type apiSimulationListItem struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Subreddit    string    `json:"subreddit"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	CommentCount int       `json:"comment_count"`
+}
+
+// Error body returned with every non-2xx API response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func registerAPIRoutes(client *openai.Client) {
+	http.HandleFunc("POST /api/v1/simulations", func(w http.ResponseWriter, r *http.Request) {
+		var req apiCreateSimulationRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeAPIError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+			return
+		}
+
+		if strings.TrimSpace(req.Prompt) == "" {
+			writeAPIError(w, http.StatusUnprocessableEntity, "prompt cannot be empty")
+			return
+		}
+		if _, ok := LookupSubreddit(req.Subreddit); !ok {
+			writeAPIError(w, http.StatusUnprocessableEntity, fmt.Sprintf("unknown subreddit %q", req.Subreddit))
+			return
+		}
+		stances, err := resolveStances(req.Options.Stances)
+		if err != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		session := NewSession(req.Prompt, req.Subreddit, CurrentUser(r))
+		session.mu.Lock()
+		session.Model = req.Options.Model
+		session.SelectedStances = stances
+		if req.Options.Seed != nil {
+			session.Seed = *req.Options.Seed
+		}
+		session.mu.Unlock()
+		log.Printf("[INFO] Created session %s via API", session.ID)
+
+		go runSimulation(client, session)
+
+		w.Header().Set("Location", "/api/v1/simulations/"+session.ID)
+		writeSimulation(w, http.StatusCreated, session)
+	})
+
+	// Lists the caller's own simulations, with the same ?q=&subreddit=&stance= filters as /history.
+	// Anonymous simulations are never listed, so there is nothing to show without a login.
+	http.HandleFunc("GET /api/v1/simulations", func(w http.ResponseWriter, r *http.Request) {
+		query := historyQueryFromRequest(r)
+		if query.Viewer == "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ShadowReddit"`)
+			writeAPIError(w, http.StatusUnauthorized, "log in to list your simulations")
+			return
+		}
+		items := SearchSessions(query)
+		writeJSON(w, http.StatusOK, map[string]any{"simulations": items})
+	})
+
+	http.HandleFunc("GET /api/v1/simulations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		session, ok := apiAuthorizeSession(w, r)
+		if !ok {
+			return
+		}
+		writeSimulation(w, http.StatusOK, session)
+	})
+
+	http.HandleFunc("POST /api/v1/simulations/{id}/fork", func(w http.ResponseWriter, r *http.Request) {
+		parent, ok := apiAuthorizeSession(w, r)
+		if !ok {
+			return
+		}
+
+		var opts ForkOptions
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&opts); err != nil && !errors.Is(err, io.EOF) {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+			return
+		}
+
+		child, err := ForkSession(parent, CurrentUser(r), opts)
+		if err != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		log.Printf("[INFO] Forked session %s from %s via API", child.ID, parent.ID)
+
+		go runSimulation(client, child)
+
+		w.Header().Set("Location", "/api/v1/simulations/"+child.ID)
+		writeSimulation(w, http.StatusCreated, child)
+	})
+
+	// Resumes a finished simulation whose stances, comments or summary failed
+	http.HandleFunc("POST /api/v1/simulations/{id}/retry", func(w http.ResponseWriter, r *http.Request) {
+		session, ok := apiAuthorizeSession(w, r)
+		if !ok {
+			return
+		}
+		if !RetrySession(client, session) {
+			writeAPIError(w, http.StatusConflict, "simulation has nothing to retry")
+			return
+		}
+		writeSimulation(w, http.StatusAccepted, session)
+	})
+
+	// Also deletes sessions the janitor already moved to SESSION_ARCHIVE_DIR
+	http.HandleFunc("DELETE /api/v1/simulations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !canDelete(id, CurrentUser(r)) {
+			writeAPIError(w, http.StatusNotFound, "simulation not found")
+			return
+		}
+		DeleteSession(id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// apiAuthorizeSession looks up the {id} path value for a viewer allowed to see it.
+// As on the HTML pages, other people's sessions are reported as not found.
+func apiAuthorizeSession(w http.ResponseWriter, r *http.Request) (*RedditSession, bool) {
+	session, ok := GetSession(r.PathValue("id"))
+	if !ok || !session.canView(CurrentUser(r)) {
+		writeAPIError(w, http.StatusNotFound, "simulation not found")
+		return nil, false
+	}
+	return session, true
+}
+
+// resolveStances checks caller-chosen stances against AllStances and fills in their summaries
+func resolveStances(requested []Stance) ([]Stance, error) {
+	var stances []Stance
+	for _, r := range requested {
+		s, ok := LookupStance(r.Type, r.SubType)
+		if !ok {
+			return nil, fmt.Errorf("unknown stance %s/%s", r.Type, r.SubType)
+		}
+		stances = append(stances, s)
+	}
+	return stances, nil
+}
+
+// writeSimulation encodes a session and writes it as the response body
+func writeSimulation(w http.ResponseWriter, status int, s *RedditSession) {
+	data, err := marshalSimulation(s, false)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to encode simulation")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// marshalSimulation encodes a session while holding its lock, since generation may still be appending to it
+func marshalSimulation(s *RedditSession, indent bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body := apiSimulation{RedditSession: s.snapshot(), Status: s.status(), Done: s.finished()}
+	if f, ok := s.fatalFailure(); ok {
+		body.Error = f.Message
+	}
+	if body.Responses == nil {
+		body.Responses = []SimulatedComment{}
+	}
+	if body.SelectedStances == nil {
+		body.SelectedStances = []Stance{}
+	}
+	if indent {
+		return json.MarshalIndent(body, "", "  ")
+	}
+	return json.Marshal(body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Error: msg})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ---------- STANCE DISTRIBUTION CHART ----------
+
+// Fill colors for each stance Type, matching the groups in stances.go
+var stanceTypeColors = map[string]string{
+	"supportive": "#16a34a",
+	"opposing":   "#dc2626",
+	"neutral":    "#9ca3af",
+	"mixed":      "#eab308",
+	"narrative":  "#9333ea",
+	"meta":       "#2563eb",
+}
+
+// StanceCount is how many of a thread's stances fall under one Type
+type StanceCount struct {
+	Type  string
+	Count int
+}
+
+// StanceDistribution counts stances by Type, ordered as the Types appear in AllStances
+func StanceDistribution(stances []Stance) []StanceCount {
+	counts := make(map[string]int)
+	for _, s := range stances {
+		counts[s.Type]++
+	}
+
+	var dist []StanceCount
+	for _, t := range StanceTypes() {
+		if counts[t] > 0 {
+			dist = append(dist, StanceCount{Type: t, Count: counts[t]})
+			delete(counts, t)
+		}
+	}
+	// GPT is told not to invent Types, but don't drop them if it does
+	var extra []string
+	for t := range counts {
+		extra = append(extra, t)
+	}
+	sort.Strings(extra)
+	for _, t := range extra {
+		dist = append(dist, StanceCount{Type: t, Count: counts[t]})
+	}
+	return dist
+}
+
+// commentStances lists the stances of the top-level comments that have one, in
+// thread order. Charts count these rather than the planned stances, which a
+// failed or cancelled thread never fully posts.
+func commentStances(comments []SimulatedComment) []Stance {
+	var stances []Stance
+	for _, c := range comments {
+		if c.Stance != nil {
+			stances = append(stances, *c.Stance)
+		}
+	}
+	return stances
+}
+
+func stanceColor(t string) string {
+	if c, ok := stanceTypeColors[t]; ok {
+		return c
+	}
+	return "#6b7280"
+}
+
+// StanceChart renders a stacked bar of the stance split. Segments and legend
+// entries carry their Type in data-filter-stance, which the page script uses to
+// filter the thread; stance text never ends up inside JavaScript.
+func StanceChart(stances []Stance) *Node {
+	dist := StanceDistribution(stances)
+	if len(dist) == 0 {
+		return Nil()
+	}
+
+	bar := Svg(Xmlns("http://www.w3.org/2000/svg"), ViewBox("0 0 100 10"),
+		Attr("preserveAspectRatio", "none"), Class("w-full h-6 rounded"))
+	legend := Div(Class("flex flex-wrap gap-2 mt-2"))
+
+	x := 0.0
+	for _, d := range dist {
+		w := float64(d.Count) * 100 / float64(len(stances))
+		bar.Children = append(bar.Children,
+			Rect(X(fmt.Sprintf("%.2f", x)), Y("0"), Width(fmt.Sprintf("%.2f", w)), Height("10"),
+				Fill(stanceColor(d.Type)), Class("cursor-pointer"), Attr("data-filter-stance", d.Type),
+				Title(Text(fmt.Sprintf("%s: %d", d.Type, d.Count))),
+			),
+		)
+		legend.Children = append(legend.Children,
+			Button(Type("button"), Class("btn btn-xs"), Attr("data-filter-stance", d.Type),
+				Span(Class("inline-block w-3 h-3 rounded-full"), Style_("background-color: "+stanceColor(d.Type))),
+				Span(Text(fmt.Sprintf("%s (%d)", d.Type, d.Count))),
+			),
+		)
+		x += w
+	}
+	legend.Children = append(legend.Children,
+		Button(Type("button"), Class("btn btn-xs btn-ghost"), Attr("data-filter-stance", ""), T("Show all")),
+	)
+
+	return Div(Class("bg-white p-4 rounded shadow"),
+		H2(Class("font-semibold text-lg mb-2"), T("Stance mix")),
+		bar,
+		legend,
+	)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- HEADLESS CLI ----------
+
+const cliUsage = `Usage:
+  shadow-reddit [serve]                     Run the web server on :8080
+  shadow-reddit simulate [flags]            Simulate one thread and print it
+  shadow-reddit batch [flags] <dir>         Simulate every .txt/.md post in a directory
+
+Run "shadow-reddit <command> -h" for command flags.
+`
+
+// runCommand dispatches a CLI subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "simulate":
+		return runSimulateCommand(args[1:])
+	case "batch":
+		return runBatchCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+}
+
+// simulate --subreddit aita --file post.txt --format markdown
+func runSimulateCommand(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	subreddit := fs.String("subreddit", "aita", "subreddit to simulate")
+	file := fs.String("file", "-", "file containing the post, or - for stdin")
+	format := fs.String("format", "markdown", "output format: markdown, json or text")
+	out := fs.String("out", "", "write the thread to this file instead of stdout")
+	model := fs.String("model", "", "override the default OpenAI models")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkCommonFlags(*subreddit, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	client, err := newOpenAIClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var post []byte
+	if *file == "-" {
+		post, err = io.ReadAll(os.Stdin)
+	} else {
+		post, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading post: %v\n", err)
+		return 1
+	}
+
+	output, err := simulatePost(client, string(post), *subreddit, *model, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *out == "" {
+		os.Stdout.Write(output)
+		return 0
+	}
+	if err := os.WriteFile(*out, output, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "writing thread: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// batch --concurrency 4 --out threads/ posts/
+func runBatchCommand(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	subreddit := fs.String("subreddit", "aita", "subreddit to simulate")
+	format := fs.String("format", "markdown", "output format: markdown, json or text")
+	out := fs.String("out", "", "directory for the generated threads (default <dir>/threads)")
+	model := fs.String("model", "", "override the default OpenAI models")
+	concurrency := fs.Int("concurrency", 4, "number of posts simulated at once")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	// Allow flags after the directory too, e.g. "batch posts/ --format json"
+	dir := fs.Arg(0)
+	if dir != "" {
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return 2
+		}
+	}
+	if dir == "" || fs.NArg() > 0 {
+		fmt.Fprint(os.Stderr, "batch needs exactly one directory\n\n"+cliUsage)
+		return 2
+	}
+	if err := checkCommonFlags(*subreddit, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	client, err := newOpenAIClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, "threads")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading %s: %v\n", dir, err)
+		return 1
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "creating %s: %v\n", *out, err)
+		return 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures int
+		sem      = make(chan struct{}, *concurrency)
+	)
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".txt" && ext != ".md") {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := simulateFile(client, filepath.Join(dir, name), *out, *subreddit, *model, *format)
+			if err != nil {
+				log.Printf("[ERROR] %s: %v", name, err)
+				mu.Lock()
+				failures++
+				mu.Unlock()
+				return
+			}
+			log.Printf("[INFO] %s done", name)
+		}(e.Name())
+	}
+	wg.Wait()
+
+	if failures > 0 {
+		fmt.Fprintf(os.Stderr, "%d post(s) failed\n", failures)
+		return 1
+	}
+	return 0
+}
+
+func simulateFile(client *openai.Client, path, outDir, subreddit, model, format string) error {
+	post, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	output, err := simulatePost(client, string(post), subreddit, model, format)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + formatExtension(format)
+	return os.WriteFile(filepath.Join(outDir, name), output, 0o644)
+}
+
+// simulatePost runs the full pipeline for one post and formats the finished thread
+func simulatePost(client *openai.Client, post, subreddit, model, format string) ([]byte, error) {
+	post = strings.TrimSpace(post)
+	if post == "" {
+		return nil, fmt.Errorf("post is empty")
+	}
+
+	sess := NewSession(post, subreddit, "")
+	// No janitor runs in CLI mode, so a batch would otherwise keep every thread in memory
+	defer removeSession(sess.ID)
+	sess.mu.Lock()
+	sess.Model = model
+	sess.mu.Unlock()
+	runSimulation(client, sess)
+
+	sess.mu.Lock()
+	failure, failed := sess.fatalFailure()
+	empty := len(sess.Responses) == 0
+	sess.mu.Unlock()
+	if failed && empty {
+		return nil, fmt.Errorf("simulation failed: %s", failure.Message)
+	}
+	if failed {
+		log.Printf("[WARN] session %s stopped early: %s", sess.ID, failure.Message)
+	}
+
+	return formatSession(sess, format)
+}
+
+// formatSession renders a finished session in one of the CLI output formats
+func formatSession(sess *RedditSession, format string) ([]byte, error) {
+	switch format {
+	case "markdown", "md":
+		return []byte(RenderMarkdown(sess)), nil
+	case "json":
+		return marshalSimulation(sess, true)
+	case "text":
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		return []byte(fmt.Sprintf("ORIGINAL POST:\n%s\n\nTHREAD:\n%s", sess.Prompt, threadTranscript(sess.Responses))), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func formatExtension(format string) string {
+	switch format {
+	case "json":
+		return ".json"
+	case "text":
+		return ".txt"
+	default:
+		return ".md"
+	}
+}
+
+func checkCommonFlags(subreddit, format string) error {
+	if _, ok := LookupSubreddit(subreddit); !ok {
+		return fmt.Errorf("unknown subreddit %q", subreddit)
+	}
+	switch format {
+	case "markdown", "md", "json", "text":
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (want markdown, json or text)", format)
+}
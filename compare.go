@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ---------- SESSION COMPARISON ----------
+
+// How many sessions fit side by side on /compare
+const maxCompareSessions = 4
+
+// A point-in-time copy of a session for the comparison page
+type compareColumn struct {
+	ID        string
+	Subreddit string
+	Model     string
+	Status    string
+	Stances   []Stance // Of the posted top-level comments
+	Comments  []SimulatedComment
+}
+
+// comparePageHandler serves /compare?ids=a,b[,c,d]
+func comparePageHandler(w http.ResponseWriter, r *http.Request) {
+	// Accept both ?ids=a,b and repeated ?ids=a&ids=b (as sent by the history page)
+	var ids []string
+	for _, param := range r.URL.Query()["ids"] {
+		for _, id := range strings.Split(param, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) < 2 || len(ids) > maxCompareSessions {
+		http.Error(w, fmt.Sprintf("Pass between 2 and %d session IDs, e.g. /compare?ids=a,b", maxCompareSessions), http.StatusBadRequest)
+		return
+	}
+
+	var columns []compareColumn
+	for _, id := range ids {
+		s, ok := authorizeSession(w, r, id)
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		columns = append(columns, compareColumn{
+			ID:        s.ID,
+			Subreddit: s.Subreddit,
+			Model:     s.Model,
+			Status:    s.status(),
+			Stances:   commentStances(s.Responses),
+			Comments:  cloneComments(s.Responses),
+		})
+		s.mu.Unlock()
+	}
+
+	ServeNode(ComparePage(columns))(w, r)
+}
+
+// ComparePage lays sessions out in columns, with stance and verdict differences on top
+func ComparePage(columns []compareColumn) *Node {
+	grid := fmt.Sprintf("display: grid; grid-template-columns: 10rem repeat(%d, minmax(0, 1fr)); gap: 1rem;", len(columns))
+
+	header := Div(Style_(grid), Div(Class("text-sm text-gray-500"), T("Session")))
+	for _, c := range columns {
+		label := c.Subreddit
+		if sub, ok := LookupSubreddit(c.Subreddit); ok {
+			label = sub.Label
+		}
+		model := c.Model
+		if model == "" {
+			model = "default model"
+		}
+		header.Children = append(header.Children,
+			Div(Class("bg-gray-100 p-3 rounded"),
+				A(Href("/session?id="+c.ID), Class("font-semibold text-blue-700 hover:underline"), Text(label)),
+				P(Class("text-xs text-gray-500"), Text(fmt.Sprintf("%s · %s · %s", c.ID, model, c.Status))),
+			),
+		)
+	}
+
+	return DefaultLayout(
+		Div(Class("mx-auto p-6 space-y-6"),
+			H1(Class("text-2xl font-bold"), T("Compare Simulations")),
+			header,
+			compareStanceTable(columns, grid),
+			compareVerdictTable(columns, grid),
+			compareThreads(columns, grid),
+			Script(Src(staticURL("compare.js"))),
+		),
+	)
+}
+
+// compareStanceTable shows how many comments each session has per stance Type
+func compareStanceTable(columns []compareColumn, grid string) *Node {
+	counts := make([]map[string]int, len(columns))
+	var all []Stance
+	for i, c := range columns {
+		counts[i] = make(map[string]int)
+		for _, d := range StanceDistribution(c.Stances) {
+			counts[i][d.Type] = d.Count
+		}
+		all = append(all, c.Stances...)
+	}
+
+	table := Div(Class("bg-white p-4 rounded shadow space-y-2"),
+		H2(Class("font-semibold text-lg"), T("Stance distribution")),
+	)
+	for _, d := range StanceDistribution(all) {
+		row := Div(Style_(grid), Class("items-center"),
+			Button(Type("button"), Class("btn btn-xs justify-start"), Attr("data-filter-stance", d.Type),
+				Span(Class("inline-block w-3 h-3 rounded-full"), Style_("background-color: "+stanceColor(d.Type))),
+				Span(Text(d.Type)),
+			),
+		)
+		row.Children = append(row.Children, compareCells(columns, func(i int) (int, string) {
+			return counts[i][d.Type], fmt.Sprintf("%d", counts[i][d.Type])
+		})...)
+		table.Children = append(table.Children, row)
+	}
+	table.Children = append(table.Children,
+		Button(Type("button"), Class("btn btn-xs btn-ghost"), Attr("data-filter-stance", ""), T("Show all stances")),
+	)
+	return table
+}
+
+// compareVerdictTable shows each session's weighted AITA tally, if any session has one
+func compareVerdictTable(columns []compareColumn, grid string) *Node {
+	judgments := make([]Judgment, len(columns))
+	hasTally := false
+	for i, c := range columns {
+		if j, ok := TallyVerdicts(c.Comments); ok {
+			judgments[i] = j
+			hasTally = true
+		}
+	}
+	if !hasTally {
+		return Nil()
+	}
+
+	table := Div(Class("bg-white p-4 rounded shadow space-y-2"),
+		H2(Class("font-semibold text-lg"), T("Verdict tally")),
+	)
+	winner := Div(Style_(grid), Span(Class("font-semibold"), T("Judgment")))
+	for _, j := range judgments {
+		if j.Total == 0 {
+			winner.Children = append(winner.Children, Span(Class("text-gray-400"), T("—")))
+			continue
+		}
+		winner.Children = append(winner.Children, Div(VerdictBadge(j.Winner.Code)))
+	}
+	table.Children = append(table.Children, winner)
+
+	for _, v := range Verdicts {
+		row := Div(Style_(grid), Span(Attr("title", v.Label), Text(v.Code)))
+		row.Children = append(row.Children, compareCells(columns, func(i int) (int, string) {
+			for _, t := range judgments[i].Tally {
+				if t.Verdict.Code == v.Code {
+					return t.Percent, fmt.Sprintf("%d%%", t.Percent)
+				}
+			}
+			return 0, "0%"
+		})...)
+		table.Children = append(table.Children, row)
+	}
+	return table
+}
+
+// compareCells renders one value per column, bolding cells that differ from the first column
+func compareCells(columns []compareColumn, value func(i int) (int, string)) []*Node {
+	first, _ := value(0)
+	var cells []*Node
+	for i := range columns {
+		n, label := value(i)
+		class := "text-gray-800"
+		if n != first {
+			class = "font-bold text-blue-700"
+		}
+		cells = append(cells, Span(Class(class), Text(label)))
+	}
+	return cells
+}
+
+// compareThreads aligns top-level comments by stance: one row per stance, one cell per session
+func compareThreads(columns []compareColumn, grid string) *Node {
+	key := func(c SimulatedComment) string {
+		if c.Stance == nil {
+			return ""
+		}
+		return c.Stance.Type + "/" + c.Stance.SubType
+	}
+
+	// Rows follow AllStances order; comments without a known stance go last
+	var rows []Stance
+	for _, s := range AllStances {
+		for _, col := range columns {
+			found := false
+			for _, c := range col.Comments {
+				if key(c) == s.Type+"/"+s.SubType {
+					found = true
+					break
+				}
+			}
+			if found {
+				rows = append(rows, s)
+				break
+			}
+		}
+	}
+	rows = append(rows, Stance{SubType: "other"})
+
+	thread := Div(Class("space-y-4"),
+		H2(Class("font-semibold text-lg"), T("Threads by stance")),
+	)
+	for _, s := range rows {
+		rowKey := s.Type + "/" + s.SubType
+		if s.Type == "" {
+			rowKey = ""
+		}
+
+		row := Div(Style_(grid), Attr("data-stance", s.Type),
+			Div(
+				P(Class("font-semibold"), Text(s.SubType)),
+				P(Class("text-xs text-gray-500"), Text(s.Type)),
+			),
+		)
+		empty := true
+		for _, col := range columns {
+			cell := Div()
+			for _, c := range col.Comments {
+				isKnown := c.Stance != nil
+				if isKnown {
+					_, isKnown = LookupStance(c.Stance.Type, c.Stance.SubType)
+				}
+				if key(c) == rowKey || (rowKey == "" && !isKnown) {
+					cell.Children = append(cell.Children, RenderCommentRecursive(c, 0))
+					empty = false
+				}
+			}
+			if len(cell.Children) == 0 {
+				cell = Div(Class("text-sm text-gray-400 italic"), T("No comment from this stance"))
+			}
+			row.Children = append(row.Children, cell)
+		}
+		if !empty {
+			thread.Children = append(thread.Children, row)
+		}
+	}
+	return thread
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ---------- SESSION EVENT LOG ----------
+
+// How much of the session has made it into its event log
+type eventCursor struct {
+	stances  bool
+	comments int
+	replies  []int // Per top-level comment
+	summary  bool
+	failures int
+	done     bool
+}
+
+// publish appends events for everything that changed since the last call and
+// wakes anyone waiting for them; callers hold s.mu
+func (s *RedditSession) publish() {
+	// Nothing follows the deleted event, even if generation was mid-step
+	if s.deleted {
+		return
+	}
+	before := len(s.Events)
+	c := &s.logged
+
+	// The planned stances first; the chart follows the comments actually posted
+	if !c.stances && len(s.SelectedStances) > 0 {
+		s.appendEvent(SessionEvent{
+			Type:    EventStances,
+			Stances: append([]Stance(nil), s.SelectedStances...),
+		})
+		c.stances = true
+	}
+
+	// New top-level comments, without their replies; those follow as reply events
+	posted := c.comments
+	for c.comments < len(s.Responses) {
+		comment := s.Responses[c.comments]
+		comment.Replies = nil
+		s.appendEvent(SessionEvent{
+			Type:    EventComment,
+			Comment: newCommentData(comment, commentID(c.comments), ""),
+			HTML:    RenderCommentRecursive(comment, 0).Render(),
+		})
+		c.replies = append(c.replies, 0)
+		c.comments++
+	}
+	if c.comments > posted {
+		stances := commentStances(s.Responses)
+		s.appendEvent(SessionEvent{Type: EventDistribution, Stances: stances, HTML: StanceChart(stances).Render()})
+	}
+
+	// New replies to any top-level comment
+	for i := range c.comments {
+		replies := s.Responses[i].Replies
+		for ; c.replies[i] < len(replies); c.replies[i]++ {
+			reply := replies[c.replies[i]]
+			s.appendEvent(SessionEvent{
+				Type:    EventReply,
+				Comment: newCommentData(reply, replyID(i, c.replies[i]), commentID(i)),
+				HTML:    RenderCommentRecursive(reply, 1).Render(),
+			})
+		}
+	}
+
+	if !c.summary && s.Summary != nil {
+		s.appendEvent(SessionEvent{Type: EventSummary, Summary: s.Summary, HTML: SummaryCard(s.Summary).Render()})
+		c.summary = true
+	}
+
+	for ; c.failures < len(s.Failures); c.failures++ {
+		f := s.Failures[c.failures]
+		id := failureID(c.failures)
+		s.appendEvent(SessionEvent{
+			Type: EventError,
+			Error: &ErrorData{
+				ID:        id,
+				Stage:     f.Stage,
+				CommentID: f.CommentID,
+				Message:   f.Message,
+				Fatal:     f.Fatal,
+				Retryable: f.retryable(),
+			},
+			HTML: FailureNotice(id, f).Render(),
+		})
+	}
+
+	// The AITA judgment needs every verdict, so it comes with the end of the thread
+	if !c.done && s.finished() {
+		if s.Subreddit == "aita" {
+			if j, ok := TallyVerdicts(s.Responses); ok {
+				s.appendEvent(SessionEvent{Type: EventJudgment, Judgment: newJudgmentData(j), HTML: JudgmentBanner(j).Render()})
+			}
+		}
+		s.appendEvent(SessionEvent{Type: EventDone, Status: s.status()})
+		c.done = true
+	}
+
+	if len(s.Events) > before {
+		s.notify()
+	}
+}
+
+// notify wakes everyone waiting for new events; callers hold s.mu
+func (s *RedditSession) notify() {
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
+
+func (s *RedditSession) appendEvent(e SessionEvent) {
+	e.V = ProtocolVersion
+	e.Seq = len(s.Events) + 1
+	s.Events = append(s.Events, e)
+}
+
+// eventsSince publishes pending changes and returns the events after seq, plus a
+// channel that is closed when more arrive; callers hold s.mu
+func (s *RedditSession) eventsSince(seq int) ([]SessionEvent, <-chan struct{}) {
+	s.publish()
+	var events []SessionEvent
+	if seq < len(s.Events) {
+		events = append(events, s.Events[max(seq, 0):]...)
+	}
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return events, s.changed
+}
+
+// How long a stream waits for new events before checking in anyway, and how long
+// a single write may take before the subscriber is given up on
+const (
+	streamIdleTimeout  = 15 * time.Second
+	streamWriteTimeout = 10 * time.Second
+)
+
+// wantsHTML reports whether a stream should include rendered fragments; ?html=0 leaves them out
+func wantsHTML(r *http.Request) bool {
+	return r.URL.Query().Get("html") != "0"
+}
+
+// streamEvents sends the session's events after seq, and how many people are
+// watching whenever that changes, until the session is deleted (after sending
+// the final deleted event), ctx ends or send fails. It keeps going after the thread is done, since OP may still reply.
+// idle, if set, runs whenever nothing happened for streamIdleTimeout, e.g. to
+// keep proxies from closing the connection.
+func streamEvents(ctx context.Context, s *RedditSession, since int, send func(SessionEvent) error, idle func() error) error {
+	s.mu.Lock()
+	s.join()
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.leave()
+		s.mu.Unlock()
+	}()
+
+	shown := 0 // Viewer count this subscriber was last told about
+	for {
+		s.mu.Lock()
+		events, changed := s.eventsSince(since)
+		viewers, deleted := s.viewers, s.deleted
+		s.mu.Unlock()
+
+		for _, e := range events {
+			if err := send(e); err != nil {
+				return err
+			}
+			since = e.Seq
+		}
+		if deleted {
+			return nil
+		}
+		if viewers != shown {
+			if err := send(presenceEvent(viewers, since)); err != nil {
+				return err
+			}
+			shown = viewers
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(streamIdleTimeout):
+			if idle != nil {
+				if err := idle(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// sseHandler serves /events?id=..., the Server-Sent Events twin of /ws for
+// networks that block WebSockets. Each logged event's seq is its SSE id, so the
+// browser's automatic reconnect resumes via Last-Event-ID; ?since= works as on /ws.
+func sseHandler(w http.ResponseWriter, r *http.Request) {
+	sess, ok := authorizeSession(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		since, _ = strconv.Atoi(last)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from holding events back
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	withHTML := wantsHTML(r)
+	streamEvents(r.Context(), sess, since, func(e SessionEvent) error {
+		if !withHTML {
+			e.HTML = ""
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if e.Type != EventPresence {
+			fmt.Fprintf(w, "id: %d\n", e.Seq)
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}, func() error {
+		_, err := fmt.Fprint(w, ": keep-alive\n\n")
+		flusher.Flush()
+		return err
+	})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ---------- EXPORT ----------
+
+type exportFormat struct {
+	Format      string
+	Label       string
+	Extension   string
+	ContentType string
+}
+
+// Export formats offered on the session page, keyed by the ?format= value
+var exportFormats = []exportFormat{
+	{Format: "md", Label: "Markdown", Extension: ".md", ContentType: "text/markdown; charset=utf-8"},
+	{Format: "json", Label: "JSON", Extension: ".json", ContentType: "application/json"},
+	{Format: "html", Label: "HTML", Extension: ".html", ContentType: "text/html; charset=utf-8"},
+}
+
+// exportCSS stands in for Tailwind/daisyUI in standalone exports, covering
+// the classes used by RenderCommentRecursive and the session cards.
+var exportCSS = func() string {
+	var b strings.Builder
+	b.WriteString(`
+body { font-family: system-ui, sans-serif; background: #f3f4f6; color: #1f2937; margin: 0; }
+h1, h2, h3, p, ul { margin: 0; }
+.max-w-2xl { max-width: 42rem; } .mx-auto { margin-left: auto; margin-right: auto; }
+.p-4 { padding: 1rem; } .p-6 { padding: 1.5rem; }
+.mt-2 { margin-top: .5rem; } .mt-3 { margin-top: .75rem; } .mb-4 { margin-bottom: 1rem; }
+.space-y-1 > * + * { margin-top: .25rem; } .space-y-6 > * + * { margin-top: 1.5rem; }
+.flex { display: flex; } .flex-wrap { flex-wrap: wrap; } .items-center { align-items: center; }
+.justify-between { justify-content: space-between; } .gap-2 { gap: .5rem; }
+.bg-white { background: #fff; } .bg-gray-100 { background: #f3f4f6; }
+.rounded { border-radius: .25rem; } .shadow { box-shadow: 0 1px 3px rgba(0,0,0,.1), 0 1px 2px rgba(0,0,0,.06); }
+.border-l-4 { border-left: 4px solid; } .border-blue-600 { border-color: #2563eb; }
+.font-semibold { font-weight: 600; } .font-bold { font-weight: 700; }
+.text-xs { font-size: .75rem; } .text-sm { font-size: .875rem; } .text-lg { font-size: 1.125rem; }
+.text-xl { font-size: 1.25rem; } .text-2xl { font-size: 1.5rem; }
+.text-blue-700 { color: #1d4ed8; } .text-gray-500 { color: #6b7280; } .text-gray-800 { color: #1f2937; }
+.whitespace-pre-wrap { white-space: pre-wrap; } .list-disc { list-style: disc; } .cursor-pointer { cursor: pointer; }
+.badge { display: inline-block; padding: 0 .5rem; border-radius: 9999px; font-size: .875rem; background: #e5e7eb; }
+.badge-sm { font-size: .75rem; }
+.badge-error { background: #fecaca; } .badge-success { background: #bbf7d0; } .badge-warning { background: #fef08a; }
+.badge-info { background: #bae6fd; } .badge-neutral { background: #374151; color: #fff; }
+`)
+	// Reply indentation, as produced by RenderCommentRecursive
+	for level := 0; level <= 10; level++ {
+		fmt.Fprintf(&b, ".ml-%d { margin-left: %grem; }\n", level*6, float64(level*6)*0.25)
+	}
+	return b.String()
+}()
+
+// StandaloneSessionPage renders a session as a single self-contained HTML document
+func StandaloneSessionPage(s *RedditSession) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	title := s.Subreddit
+	if sub, ok := LookupSubreddit(s.Subreddit); ok {
+		title = sub.Label
+	}
+
+	return Html(
+		Head(
+			Meta(Charset("UTF-8")),
+			Title(Text(fmt.Sprintf("%s simulation — ShadowReddit", title))),
+			Style(Raw(exportCSS)),
+		),
+		Body(
+			Div(Class("max-w-2xl mx-auto p-6 space-y-6"),
+				H1(Class("text-2xl font-bold"), Text(fmt.Sprintf("%s simulation", title))),
+				Ch(staticThread(s)),
+				P(Class("text-xs text-gray-500"), T("Exported from ShadowReddit. ShadowReddit is not affiliated with Reddit in anyway.")),
+			),
+		),
+	)
+}
+
+// staticThread renders the judgment, summary, post and comments of a session
+// without any live updates; callers hold s.mu
+func staticThread(s *RedditSession) []*Node {
+	var judgment, summary *Node = Nil(), Nil()
+	if s.Subreddit == "aita" {
+		if j, ok := TallyVerdicts(s.Responses); ok {
+			judgment = JudgmentBanner(j)
+		}
+	}
+	if s.Summary != nil {
+		summary = SummaryCard(s.Summary)
+	}
+
+	comments := Div(Id("responseArea"))
+	for _, c := range s.Responses {
+		comments.Children = append(comments.Children, RenderCommentRecursive(c, 0))
+	}
+
+	return []*Node{
+		judgment,
+		summary,
+		Div(Class("bg-gray-100 p-4 rounded"),
+			H2(Class("font-semibold text-lg"), T("The Post")),
+			P(Class("mt-2 whitespace-pre-wrap text-gray-800"), Text(s.Prompt)),
+		),
+		comments,
+	}
+}
+
+// ExportLinks renders the download buttons for a session
+func ExportLinks(sessionID string) *Node {
+	links := Div(Class("flex flex-wrap items-center gap-2 text-sm"),
+		Span(Class("text-gray-500"), T("Download:")),
+	)
+	for _, f := range exportFormats {
+		links.Children = append(links.Children,
+			A(Href(fmt.Sprintf("/export?id=%s&format=%s", sessionID, f.Format)), Attr("download", ""),
+				Class("btn btn-xs btn-outline"), Text(f.Label)),
+		)
+	}
+	return links
+}
+
+func setExportHeaders(w http.ResponseWriter, s *RedditSession, f exportFormat) {
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="shadow-reddit-%s%s"`, s.ID, f.Extension))
+}
+
+// exportHandler serves /export?id=...&format=md|json|html as a file download
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := authorizeSession(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	for _, f := range exportFormats {
+		if f.Format != format {
+			continue
+		}
+
+		var body []byte
+		switch format {
+		case "md":
+			body = []byte(RenderMarkdown(session))
+		case "json":
+			data, err := marshalSimulation(session, true)
+			if err != nil {
+				http.Error(w, "Error encoding session", http.StatusInternalServerError)
+				return
+			}
+			body = data
+		case "html":
+			// Big threads go straight to the client instead of through one string
+			page := StandaloneSessionPage(session)
+			setExportHeaders(w, session, f)
+			io.WriteString(w, "<!DOCTYPE html>\n")
+			if err := page.RenderTo(r.Context(), w); err != nil {
+				log.Printf("[ERROR] exporting session %s: %v", session.ID, err)
+			}
+			return
+		}
+
+		setExportHeaders(w, session, f)
+		w.Write(body)
+		return
+	}
+	http.Error(w, "Unknown export format", http.StatusBadRequest)
+}
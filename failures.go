@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- GENERATION FAILURES ----------
+
+// Simulation steps that can fail
+const (
+	StageStances = "stances"
+	StageComment = "comment"
+	StageReply   = "reply"
+	StageSummary = "summary"
+)
+
+// GenerationFailure is a step of the simulation that didn't work out. Message is
+// safe to show to users; the underlying error only goes to the server log.
+type GenerationFailure struct {
+	Stage     string `json:"stage"`
+	CommentID string `json:"comment_id,omitempty"` // The comment a failed reply was meant for
+	Message   string `json:"message"`
+	Fatal     bool   `json:"fatal"`              // Generation stopped here
+	Resolved  bool   `json:"resolved,omitempty"` // A retry was started since
+}
+
+// What the user is told for each stage, before the likely cause
+var failureMessages = map[string]string{
+	StageStances: "Couldn't pick the commenters for this thread, so nothing was generated.",
+	StageComment: "Generation stopped before every commenter had their say.",
+	StageReply:   "A reply to this comment couldn't be generated.",
+	StageSummary: "The thread summary couldn't be generated.",
+}
+
+// recordFailure notes a failed step; callers hold s.mu
+func (s *RedditSession) recordFailure(stage, commentID string, err error, fatal bool) {
+	s.Failures = append(s.Failures, GenerationFailure{
+		Stage:     stage,
+		CommentID: commentID,
+		Message:   failureMessages[stage] + " " + failureCause(err),
+		Fatal:     fatal,
+	})
+}
+
+// fatalFailure returns the failure that stopped generation, if any; callers hold s.mu
+func (s *RedditSession) fatalFailure() (GenerationFailure, bool) {
+	for i := len(s.Failures) - 1; i >= 0; i-- {
+		if f := s.Failures[i]; f.Fatal && !f.Resolved {
+			return f, true
+		}
+	}
+	return GenerationFailure{}, false
+}
+
+// failureCause explains an OpenAI error without passing its details on
+func failureCause(err error) string {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	status := 0
+	if errors.As(err, &apiErr) {
+		status = apiErr.HTTPStatusCode
+	} else if errors.As(err, &reqErr) {
+		status = reqErr.HTTPStatusCode
+	}
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "The server's OpenAI key was rejected."
+	case status == http.StatusTooManyRequests:
+		return "The model is rate limited right now; wait a minute and retry."
+	case status >= 500:
+		return "The model service is having problems; retry in a moment."
+	default:
+		return "The model couldn't be reached or gave an unusable answer."
+	}
+}
+
+// retryable reports whether retrying could fill in what a failure left out
+func (f GenerationFailure) retryable() bool {
+	return !f.Resolved && f.Stage != StageReply
+}
+
+// canRetry reports whether the session finished with something a retry could fix; callers hold s.mu
+func (s *RedditSession) canRetry() bool {
+	if (s.State != StateDone && s.State != StateFailed) || s.deleted {
+		return false
+	}
+	for _, f := range s.Failures {
+		if f.retryable() {
+			return true
+		}
+	}
+	return false
+}
+
+// threadIncomplete reports whether runSimulation would still add comments or
+// replies to the session; callers hold s.mu
+func (s *RedditSession) threadIncomplete() bool {
+	if len(s.SelectedStances) == 0 || len(s.Responses) < len(s.SelectedStances) {
+		return true
+	}
+	for _, c := range s.Responses {
+		if len(c.Replies) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RetrySession resumes a finished session that had failures, keeping whatever
+// was already generated. It reports false when there is nothing to retry.
+func RetrySession(client *openai.Client, s *RedditSession) bool {
+	s.mu.Lock()
+	if !s.canRetry() {
+		s.mu.Unlock()
+		return false
+	}
+	for i := range s.Failures {
+		s.Failures[i].Resolved = true
+	}
+	if s.threadIncomplete() {
+		// The summary described the partial thread; a new one follows once it is filled in
+		s.Summary = nil
+		s.logged.summary = false
+	}
+	s.logged.done = false
+	s.appendEvent(SessionEvent{Type: EventRetrying})
+	s.notify()
+	s.setState(StatePending)
+	s.mu.Unlock()
+
+	log.Printf("[INFO] Retrying session %s", s.ID)
+	go runSimulation(client, s)
+	return true
+}
+
+// FailureNotice renders one failure; id matches the "error" event's error.id
+func FailureNotice(id string, f GenerationFailure) *Node {
+	alert := "alert-warning"
+	if f.Fatal {
+		alert = "alert-error"
+	}
+	return Div(Id("failure-"+id), Class("generation-failure alert "+alert+" text-sm my-2"), Role("alert"),
+		Span(Text(f.Message)),
+		If(f.retryable(), Button(Type("button"), Class("btn btn-sm"), OnClick("retrySession()"), T("Retry")), Nil()),
+	)
+}
+
+// failureID names the n-th failure of a session
+func failureID(n int) string {
+	return fmt.Sprintf("f%d", n)
+}
+
+// FailureNotices renders the failures no retry has dealt with yet; callers hold s.mu
+func FailureNotices(s *RedditSession) *Node {
+	area := Div(Id("errorArea"))
+	for i, f := range s.Failures {
+		if !f.Resolved {
+			area.Children = append(area.Children, FailureNotice(failureID(i), f))
+		}
+	}
+	return area
+}
+
+// retryHandler serves POST /retry?id=..., used by the Retry button on the session page
+func retryHandler(client *openai.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		session, ok := authorizeSession(w, r, r.URL.Query().Get("id"))
+		if !ok {
+			return
+		}
+		if !RetrySession(client, session) {
+			http.Error(w, "Nothing to retry", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- FORKING ----------
+
+// ForkOptions says what changes between a session and its fork.
+// Zero values keep the parent's settings.
+type ForkOptions struct {
+	Subreddit    string   `json:"subreddit,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Stances      []Stance `json:"stances,omitempty"`       // Explicit stance mix for the new comments
+	FreshStances bool     `json:"fresh_stances,omitempty"` // Let GPT choose new stances instead of reusing the parent's
+	KeepComments bool     `json:"keep_comments,omitempty"` // Copy the parent's comments into the fork
+}
+
+// ForkSession copies parent's prompt (and optionally its comments) into a new
+// session owned by owner and linked back to parent. The caller starts generation.
+func ForkSession(parent *RedditSession, owner string, opts ForkOptions) (*RedditSession, error) {
+	prompt := parent.Prompt
+	subreddit := parent.Subreddit
+	parent.mu.Lock()
+	model := parent.Model
+	parentStances := append([]Stance(nil), parent.SelectedStances...)
+	comments := cloneComments(parent.Responses)
+	parent.mu.Unlock()
+
+	if opts.Subreddit != "" {
+		subreddit = opts.Subreddit
+	}
+	if _, ok := LookupSubreddit(subreddit); !ok {
+		return nil, fmt.Errorf("unknown subreddit %q", subreddit)
+	}
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	requested, err := resolveStances(opts.Stances)
+	if err != nil {
+		return nil, err
+	}
+	if opts.KeepComments && opts.FreshStances {
+		// Kept comments fix the stances, and GPT only picks stances for an empty thread
+		return nil, fmt.Errorf("new stances can't be chosen for kept comments; pick the added stances explicitly instead")
+	}
+
+	var stances []Stance
+	switch {
+	case opts.KeepComments:
+		// Comment i was written from stance i, so kept comments keep their stances
+		// and any requested stances become additional comments after them.
+		stances = parentStances[:min(len(comments), len(parentStances))]
+		stances = append(stances, requested...)
+	case len(requested) > 0:
+		stances = requested
+	case !opts.FreshStances:
+		stances = parentStances
+	}
+	if !opts.KeepComments {
+		comments = nil
+	}
+
+	child := NewSession(prompt, subreddit, owner)
+	child.mu.Lock()
+	child.ParentID = parent.ID
+	child.Model = model
+	child.SelectedStances = stances
+	child.Responses = comments
+	child.mu.Unlock()
+	return child, nil
+}
+
+// cloneComments deep-copies a comment tree so a fork's replies never share backing arrays with its parent
+func cloneComments(comments []SimulatedComment) []SimulatedComment {
+	if comments == nil {
+		return nil
+	}
+	out := make([]SimulatedComment, len(comments))
+	for i, c := range comments {
+		out[i] = c
+		if c.Stance != nil {
+			stance := *c.Stance
+			out[i].Stance = &stance
+		}
+		out[i].Replies = cloneComments(c.Replies)
+	}
+	return out
+}
+
+// sessionForks lists the sessions forked from id that user may see, oldest first
+func sessionForks(id, user string) []*RedditSession {
+	var forks []*RedditSession
+	for _, s := range allSessions() {
+		s.mu.Lock()
+		parentID := s.ParentID
+		s.mu.Unlock()
+		if parentID == id && s.canView(user) {
+			forks = append(forks, s)
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool {
+		return forks[i].CreatedAt.Before(forks[j].CreatedAt)
+	})
+	return forks
+}
+
+// ForkForm renders the "Fork" action on the session page
+func ForkForm(sessionID, subreddit, model string) *Node {
+	stanceSelect := Select(Name("stance"), Id("fork-stance"), Multiple(true), Attr("size", "8"),
+		Class("w-full border rounded p-2 text-sm"))
+	for _, s := range AllStances {
+		stanceSelect.Children = append(stanceSelect.Children,
+			Option(Value(s.Type+"/"+s.SubType), Text(fmt.Sprintf("%s — %s", s.Type, s.SubType))),
+		)
+	}
+
+	return Details(Class("bg-white p-4 rounded shadow"),
+		Summary(Class("cursor-pointer font-semibold"), T("Fork this thread")),
+		P(Class("text-sm text-gray-500 mt-2"),
+			T("Re-run the same post with a different subreddit, stance mix or model. The fork links back here."),
+		),
+		Form(Method("POST"), Action("/fork"), Class("mt-4 space-y-4"),
+			Input(Type("hidden"), Name("id"), Value(sessionID)),
+			Div(
+				Label(For("subreddit"), Class("block font-medium mb-1"), T("Subreddit")),
+				SubredditSelect(subreddit),
+			),
+			Div(
+				Label(For("fork-model"), Class("block font-medium mb-1"), T("Model (blank for default)")),
+				Input(Type("text"), Id("fork-model"), Name("model"), Value(model), Class("w-full border rounded p-2")),
+			),
+			Div(
+				Label(For("fork-stances"), Class("block font-medium mb-1"), T("Stances")),
+				Select(Name("stances"), Id("fork-stances"), Class("w-full border rounded p-2"),
+					Option(Value("keep"), T("Keep this thread's stances")),
+					Option(Value("fresh"), T("Let GPT choose new stances")),
+					Option(Value("custom"), T("Use the stances selected below")),
+				),
+				stanceSelect,
+			),
+			Label(Class("flex items-center gap-2"),
+				Input(Type("checkbox"), Name("keep_comments"), Value("1"), Class("checkbox")),
+				T("Copy the existing comments into the fork"),
+			),
+			Button(Type("submit"), Class("bg-blue-600 text-white px-4 py-2 rounded"), T("Fork")),
+		),
+	)
+}
+
+// LineageLinks shows where a session was forked from and what was forked from it
+func LineageLinks(sessionID, parentID string, forkIDs []string) *Node {
+	if parentID == "" && len(forkIDs) == 0 {
+		return Nil()
+	}
+
+	lineage := Div(Class("text-sm text-gray-500 space-y-1"))
+	if parentID != "" {
+		lineage.Children = append(lineage.Children,
+			P(T("Forked from "), A(Href("/session?id="+parentID), Class("text-blue-600 hover:underline"), Text(parentID))),
+		)
+	}
+	if len(forkIDs) > 0 {
+		forks := P(T("Forks: "))
+		for i, id := range forkIDs {
+			if i > 0 {
+				forks.Children = append(forks.Children, Span(T(", ")))
+			}
+			forks.Children = append(forks.Children,
+				A(Href("/session?id="+id), Class("text-blue-600 hover:underline"), Text(id)),
+			)
+		}
+		lineage.Children = append(lineage.Children, forks)
+	}
+
+	// Compare this session with its parent and forks, as many as fit side by side
+	related := []string{sessionID}
+	if parentID != "" {
+		related = append(related, parentID)
+	}
+	related = append(related, forkIDs...)
+	related = related[:min(len(related), maxCompareSessions)]
+	lineage.Children = append(lineage.Children,
+		A(Href("/compare?ids="+strings.Join(related, ",")), Class("text-blue-600 hover:underline"), T("Compare side by side")),
+	)
+	return lineage
+}
+
+// forkHandler handles the Fork form on the session page
+func forkHandler(client *openai.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		parent, ok := authorizeSession(w, r, r.FormValue("id"))
+		if !ok {
+			return
+		}
+
+		opts := ForkOptions{
+			Subreddit:    r.FormValue("subreddit"),
+			Model:        strings.TrimSpace(r.FormValue("model")),
+			KeepComments: r.FormValue("keep_comments") != "",
+		}
+		switch r.FormValue("stances") {
+		case "fresh":
+			opts.FreshStances = true
+		case "custom":
+			for _, v := range r.Form["stance"] {
+				stanceType, subType, _ := strings.Cut(v, "/")
+				opts.Stances = append(opts.Stances, Stance{Type: stanceType, SubType: subType})
+			}
+			if len(opts.Stances) == 0 {
+				http.Error(w, "Select at least one stance", http.StatusBadRequest)
+				return
+			}
+		}
+
+		child, err := ForkSession(parent, CurrentUser(r), opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		log.Printf("[INFO] Forked session %s from %s", child.ID, parent.ID)
+
+		go runSimulation(client, child)
+		http.Redirect(w, r, "/session?id="+child.ID, http.StatusSeeOther)
+	}
+}
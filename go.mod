@@ -5,4 +5,5 @@ go 1.24.1
 require (
 	github.com/gorilla/websocket v1.5.3
 	github.com/sashabaranov/go-openai v1.38.1
+	golang.org/x/crypto v0.45.0
 )
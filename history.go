@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ---------- SESSION HISTORY ----------
+
+// HistoryQuery filters the session listing; empty fields match everything
+type HistoryQuery struct {
+	Text      string // Case-insensitive search over the post and every comment
+	Subreddit string
+	Stance    string // Stance Type, e.g. "supportive"
+	Viewer    string // Logged-in user; only their own sessions are listed
+}
+
+// historyQueryFromRequest reads ?q=&subreddit=&stance= from a request
+func historyQueryFromRequest(r *http.Request) HistoryQuery {
+	q := r.URL.Query()
+	return HistoryQuery{
+		Text:      strings.TrimSpace(q.Get("q")),
+		Subreddit: q.Get("subreddit"),
+		Stance:    q.Get("stance"),
+		Viewer:    CurrentUser(r),
+	}
+}
+
+// SearchSessions lists the viewer's matching sessions, newest first. Anonymous
+// sessions belong to no one, so they are never listed: their unguessable ID is
+// all that protects them. Logged-out viewers get an empty list.
+func SearchSessions(query HistoryQuery) []apiSimulationListItem {
+	needle := strings.ToLower(query.Text)
+
+	items := []apiSimulationListItem{}
+	if query.Viewer == "" {
+		return items
+	}
+	for _, s := range allSessions() {
+		if s.Owner != query.Viewer {
+			continue
+		}
+		if query.Subreddit != "" && s.Subreddit != query.Subreddit {
+			continue
+		}
+
+		s.mu.Lock()
+		if query.Stance != "" && !hasStanceType(s, query.Stance) {
+			s.mu.Unlock()
+			continue
+		}
+		if needle != "" && !strings.Contains(strings.ToLower(s.Prompt), needle) && !commentsContain(s.Responses, needle) {
+			s.mu.Unlock()
+			continue
+		}
+		items = append(items, apiSimulationListItem{
+			ID:           s.ID,
+			CreatedAt:    s.CreatedAt,
+			Subreddit:    s.Subreddit,
+			Title:        firstLine(s.Prompt),
+			Status:       s.status(),
+			CommentCount: countComments(s.Responses),
+		})
+		s.mu.Unlock()
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+	return items
+}
+
+// hasStanceType reports whether any of the session's stances has the given Type; callers hold s.mu
+func hasStanceType(s *RedditSession, stanceType string) bool {
+	for _, st := range s.SelectedStances {
+		if st.Type == stanceType {
+			return true
+		}
+	}
+	for _, c := range s.Responses {
+		if c.Stance != nil && c.Stance.Type == stanceType {
+			return true
+		}
+	}
+	return false
+}
+
+// commentsContain searches usernames and text of a comment tree for a lower-cased needle
+func commentsContain(comments []SimulatedComment, needle string) bool {
+	for _, c := range comments {
+		if strings.Contains(strings.ToLower(c.Text), needle) || strings.Contains(strings.ToLower(c.Username), needle) {
+			return true
+		}
+		if commentsContain(c.Replies, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// historyPageHandler serves /history with the same filters as GET /api/v1/simulations
+func historyPageHandler(w http.ResponseWriter, r *http.Request) {
+	query := historyQueryFromRequest(r)
+	ServeNode(HistoryPage(query, SearchSessions(query)))(w, r)
+}
+
+// HistoryPage lists past sessions with search and filters
+func HistoryPage(query HistoryQuery, items []apiSimulationListItem) *Node {
+	subreddits := Select(Name("subreddit"), Class("border rounded p-2"), Option(Value(""), T("All subreddits")))
+	for _, sub := range Subreddits {
+		opt := Option(Value(sub.Name), Selected(sub.Name == query.Subreddit), Text(sub.Label))
+		subreddits.Children = append(subreddits.Children, opt)
+	}
+	stances := Select(Name("stance"), Class("border rounded p-2"), Option(Value(""), T("All stances")))
+	for _, t := range StanceTypes() {
+		opt := Option(Value(t), Selected(t == query.Stance), Text(t))
+		stances.Children = append(stances.Children, opt)
+	}
+
+	rows := Tbody()
+	for _, item := range items {
+		rows.Children = append(rows.Children, Tr(
+			Td(Input(Type("checkbox"), Name("ids"), Value(item.ID), Class("checkbox checkbox-sm"), AriaLabel("Compare"))),
+			Td(Class("whitespace-nowrap text-sm text-gray-500"), Text(item.CreatedAt.Format("2006-01-02 15:04"))),
+			Td(Text(item.Subreddit)),
+			Td(A(Href("/session?id="+item.ID), Class("text-blue-600 hover:underline"), Text(item.Title))),
+			Td(Span(Class("badge badge-sm "+statusBadge(item.Status)), Text(item.Status))),
+			Td(Class("text-right"), Text(fmt.Sprintf("%d", item.CommentCount))),
+		))
+	}
+
+	var results *Node
+	if query.Viewer == "" {
+		results = P(Class("text-gray-500 italic"),
+			T("Sessions started while logged out are only reachable through their own links. "),
+			A(Href("/login?next=/history"), Class("text-blue-600 hover:underline"), T("Log in to see yours.")),
+		)
+	} else if len(items) == 0 {
+		results = P(Class("text-gray-500 italic"), T("No sessions match."))
+	} else {
+		results = Form(Method("GET"), Action("/compare"), Class("space-y-2"),
+			Table(Class("table w-full"),
+				Thead(Tr(Th(AriaLabel("Compare")), Th(T("Created")), Th(T("Subreddit")), Th(T("Post")), Th(T("Status")), Th(Class("text-right"), T("Comments")))),
+				rows,
+			),
+			Button(Type("submit"), Class("btn btn-sm"), T("Compare selected")),
+		)
+	}
+
+	return DefaultLayout(
+		Main(Class("max-w-4xl mx-auto p-8 space-y-6"),
+			H1(Class("text-2xl font-bold"), T("Session History")),
+			Form(Method("GET"), Action("/history"), Class("flex flex-wrap gap-2"),
+				Input(Type("search"), Name("q"), Value(query.Text), Placeholder("Search posts and comments"),
+					Class("border rounded p-2 flex-1")),
+				subreddits,
+				stances,
+				Button(Type("submit"), Class("bg-blue-600 text-white px-4 py-2 rounded"), T("Search")),
+			),
+			results,
+		),
+	)
+}
+
+func statusBadge(status string) string {
+	switch status {
+	case "done":
+		return "badge-success"
+	case "failed":
+		return "badge-error"
+	default:
+		return "badge-info"
+	}
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"html/template"
+	"io"
+	"log"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -145,23 +151,23 @@ func RenderHTML() *Node {
 
 func ServeNode(n *Node) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusOK)
-		_, err := w.Write([]byte(n.Render()))
-		if err != nil {
-			http.Error(w, "Error rendering HTML", http.StatusInternalServerError)
-		}
+		writePage(r.Context(), w, n)
 	}
 }
 
 func ServeNodeCtx(ctx context.Context, n *Node) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusOK)
-		_, err := w.Write([]byte(n.RenderCtx(ctx)))
-		if err != nil {
-			http.Error(w, "Error rendering HTML", http.StatusInternalServerError)
-		}
+		writePage(ctx, w, n)
+	}
+}
+
+// writePage streams n as the response. The status is already sent by the time
+// rendering can fail, so a failure (usually the client going away) is only logged.
+func writePage(ctx context.Context, w http.ResponseWriter, n *Node) {
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	if err := n.RenderTo(ctx, w); err != nil {
+		log.Printf("[ERROR] rendering HTML: %v", err)
 	}
 }
 
@@ -186,58 +192,57 @@ func (s *Node) Init(n *Node) {
 }
 
 func (s *Node) RenderPage(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	_, err := fmt.Fprint(w, s.Render())
-	if err != nil {
-		http.Error(w, "Error rendering HTML", http.StatusInternalServerError)
-	}
+	writePage(r.Context(), w, s)
 }
 
 func (s *Node) RenderPageCtx(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(s.RenderCtx(ctx)))
-	if err != nil {
-		http.Error(w, "Error rendering HTML", http.StatusInternalServerError)
-	}
+	writePage(ctx, w, s)
 }
 
 func Render(root string, w http.ResponseWriter, s *Node) {
-	ctx := context.WithValue(context.Background(), "baseURL", root)
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(s.RenderCtx(ctx)))
-	if err != nil {
-		http.Error(w, "Error rendering HTML", http.StatusInternalServerError)
-	}
+	writePage(context.WithValue(context.Background(), "baseURL", root), w, s)
 }
 
-// TODO breadchris rewrite this using io.Writer
-func (s *Node) RenderCtx(ctx context.Context) string {
-	c := ""
-	if s.text != "" {
-		c += template.HTMLEscapeString(s.text)
+// RenderTo writes the node's HTML to w through a buffer, so even a huge page
+// never exists as one string. It stops early if ctx is cancelled.
+func (s *Node) RenderTo(ctx context.Context, w io.Writer) error {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
 	}
-	if s.raw != "" {
-		c += s.raw
+	if err := s.renderTo(ctx, bw); err != nil {
+		return err
 	}
+	return bw.Flush()
+}
+
+// Elements that never have content or a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
 
+// Attributes that are on by being present; they render as a bare name
+var booleanAttrs = map[string]bool{
+	"async": true, "autofocus": true, "checked": true, "defer": true, "disabled": true, "hidden": true,
+	"multiple": true, "novalidate": true, "open": true, "readonly": true, "required": true, "selected": true,
+}
+
+func (s *Node) renderTo(ctx context.Context, w *bufio.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if s.baseURL != "" {
 		ctx = context.WithValue(ctx, "baseURL", s.baseURL)
 	}
 
-	for _, t := range s.Children {
-		c += t.RenderCtx(ctx)
-	}
-
-	if len(s.Attrs) == 0 && len(s.DynamicAttrs) == 0 {
-		if c == "" {
-			return ""
-		}
-		return fmt.Sprintf("<%s>%s</%s>", s.Name, c, s.Name)
+	// A nameless node (e.g. Nil) is only its content
+	if s.Name == "" {
+		return s.renderContent(ctx, w)
 	}
 
+	w.WriteString("<")
+	w.WriteString(s.Name)
 	attrs := map[string]string{}
 	if s.locator != "" {
 		attrs["data-godom"] = s.locator
@@ -248,19 +253,48 @@ func (s *Node) RenderCtx(ctx context.Context) string {
 	for k, v := range s.Attrs {
 		attrs[k] = v
 	}
+	// Sorted, so the same node always renders to the same bytes
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		w.WriteString(" ")
+		w.WriteString(k)
+		if booleanAttrs[k] {
+			continue
+		}
+		w.WriteString(`="`)
+		template.HTMLEscape(w, []byte(attrs[k]))
+		w.WriteString(`"`)
+	}
+	_, err := w.WriteString(">")
+	if voidElements[s.Name] {
+		return err
+	}
+
+	if err := s.renderContent(ctx, w); err != nil {
+		return err
+	}
+	w.WriteString("</")
+	w.WriteString(s.Name)
+	_, err = w.WriteString(">")
+	return err
+}
 
-	a := ""
-	var i int
-	for k, v := range attrs {
-		v = template.HTMLEscapeString(v)
-		if i == len(attrs)-1 {
-			a += fmt.Sprintf("%s=\"%s\"", k, v)
-			break
+func (s *Node) renderContent(ctx context.Context, w *bufio.Writer) error {
+	if s.text != "" {
+		template.HTMLEscape(w, []byte(s.text))
+	}
+	w.WriteString(s.raw)
+	for _, t := range s.Children {
+		if err := t.renderTo(ctx, w); err != nil {
+			return err
 		}
-		a += fmt.Sprintf("%s=\"%s\" ", k, v)
-		i++
 	}
-	return fmt.Sprintf("<%s %s>%s</%s>", s.Name, a, c, s.Name)
+	return nil
+}
+
+func (s *Node) RenderCtx(ctx context.Context) string {
+	var b strings.Builder
+	s.RenderTo(ctx, &b)
+	return b.String()
 }
 
 func (s *Node) Render() string {
@@ -296,13 +330,28 @@ func RenderGoFunction(fset *token.FileSet, name string, s ast.Expr) *ast.FuncDec
 	}
 }
 
+// JSONScript embeds v as <script type="application/json" id="...">, for page scripts
+// to read with JSON.parse(document.getElementById(id).textContent). json.Marshal
+// escapes <, > and &, so no value can end the element early.
+func JSONScript(id string, v any) *Node {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("[ERROR] embedding script data %s: %v", id, err)
+		data = []byte("null")
+	}
+	return Script(Type("application/json"), Id(id), Raw(string(data)))
+}
+
+// ReloadNode reloads the page when the dev server reports that filename changed.
+// The name travels in a data attribute, which the renderer escapes like any other.
 func ReloadNode(filename string) *Node {
-	return Script(Raw(fmt.Sprintf(`
-const ws = new WebSocket("ws://localhost:8080/reload/");
+	return Script(Attr("data-file", filename), Raw(`
+const reloadFile = document.currentScript.dataset.file;
+const ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/reload/");
 
 ws.onmessage = function (event) {
     const msg = JSON.parse(event.data);
-	if (msg.type === "reload" && msg.data === "%s") {
+	if (msg.type === "reload" && msg.data === reloadFile) {
 		ws.close();
 		console.log("File changed, reloading...");
 		window.location.reload();
@@ -316,7 +365,7 @@ ws.onclose = function () {
 ws.onerror = function (error) {
 	console.error("WebSocket error:", error);
 };
-`, filename)))
+`))
 }
 
 func (s *Node) RenderGoCode(fset *token.FileSet) *ast.CallExpr {
@@ -334,7 +383,8 @@ func (s *Node) RenderGoCode(fset *token.FileSet) *ast.CallExpr {
 		Args: []ast.Expr{},
 	}
 
-	for k, v := range s.Attrs {
+	for _, k := range slices.Sorted(maps.Keys(s.Attrs)) {
+		v := s.Attrs[k]
 		var cased []string
 		for _, st := range strings.Split(k, "-") {
 			cased = append(cased, strings.Title(st))
@@ -483,10 +533,31 @@ func Cha(c []*Node) *Node {
 }
 
 func Checked(b bool) *Node {
+	return BoolAttr("checked", b)
+}
+
+func Disabled(b bool) *Node {
+	return BoolAttr("disabled", b)
+}
+
+func Required(b bool) *Node {
+	return BoolAttr("required", b)
+}
+
+func Multiple(b bool) *Node {
+	return BoolAttr("multiple", b)
+}
+
+func Selected(b bool) *Node {
+	return BoolAttr("selected", b)
+}
+
+// BoolAttr sets a boolean attribute such as disabled when on, and leaves it out otherwise
+func BoolAttr(k string, on bool) *Node {
 	return &Node{
 		transform: func(p *Node) {
-			if b {
-				p.Attrs["checked"] = "checked"
+			if on {
+				p.Attrs[k] = ""
 			}
 		},
 	}
@@ -852,13 +923,7 @@ func Summary(o ...*Node) *Node {
 }
 
 func Open(b bool) *Node {
-	return &Node{
-		transform: func(p *Node) {
-			if b {
-				p.Attrs["open"] = ""
-			}
-		},
-	}
+	return BoolAttr("open", b)
 }
 
 func Method(s string) *Node {
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata golden files")
+
+func TestRenderSortedAttributes(t *testing.T) {
+	want := `<div aria-label="l" class="c" data-z="z" id="x"></div>`
+	// Attributes live in a map, so render often enough to hit different iteration orders
+	for range 20 {
+		for _, node := range []*Node{
+			Div(Id("x"), Class("c"), Attr("aria-label", "l"), Attr("data-z", "z")),
+			Div(Attr("data-z", "z"), Attr("aria-label", "l"), Class("c"), Id("x")),
+		} {
+			if got := node.Render(); got != want {
+				t.Fatalf("got  %s\nwant %s", got, want)
+			}
+		}
+	}
+}
+
+func TestRenderCommentRecursive(t *testing.T) {
+	stance := AllStances[0]
+	comment := SimulatedComment{
+		Username: "supportive_strong_agreement",
+		Flair:    "supportive",
+		Stance:   &stance,
+		Text:     "NTA. Your roommate owes you <dinner> & an apology.",
+		Verdict:  VerdictNTA,
+		Score:    42,
+		Replies: []SimulatedComment{
+			{Username: "CuriousCat", Flair: "reply", Text: "Did you label the leftovers?",
+				Replies: []SimulatedComment{{Username: "OP", Flair: "OP", Text: "I did!"}}},
+			{Username: "HonestAbe", Flair: "reply", Text: "Agreed."},
+		},
+	}
+	got := RenderCommentRecursive(comment, 0).Render()
+
+	golden := filepath.Join("testdata", "comment.golden.html")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strings.TrimSuffix(string(want), "\n") {
+		t.Errorf("rendered comment differs from %s (go test -run %s -update to accept):\n%s", golden, t.Name(), got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		node *Node
+		want string
+	}{
+		{"boolean attribute", Input(Checked(true), Type("checkbox")), `<input checked type="checkbox">`},
+		{"boolean attribute off", Input(Type("checkbox"), Checked(false)), `<input type="checkbox">`},
+		{"void element", Hr(), `<hr>`},
+		{"empty element", Div(), `<div></div>`},
+		{"escaped attribute", Div(Attr("title", `"><script>`)), `<div title="&#34;&gt;&lt;script&gt;"></div>`},
+		{"escaped text", P(T("<b>&</b>")), `<p>&lt;b&gt;&amp;&lt;/b&gt;</p>`},
+		{"nil", Div(Nil(), Span(T("a"))), `<div><span>a</span></div>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.Render(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONScript(t *testing.T) {
+	v := map[string]string{"text": "</script><script>alert(1)</script>"}
+	got := JSONScript("data", v).Render()
+
+	body := strings.TrimSuffix(strings.TrimPrefix(got, `<script id="data" type="application/json">`), "</script>")
+	if body == got || strings.Contains(strings.ToLower(body), "</script") {
+		t.Fatalf("data can end the script element: %s", got)
+	}
+	var back map[string]string
+	if err := json.Unmarshal([]byte(body), &back); err != nil || back["text"] != v["text"] {
+		t.Errorf("data doesn't round-trip: %v, %q", err, back["text"])
+	}
+}
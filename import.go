@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- IMPORT ----------
+
+// Maximum size of an uploaded session document
+const importMaxBytes = 1 << 20
+
+// Page for uploading a previously exported (or hand-written) session
+func ImportPage() *Node {
+	return DefaultLayout(
+		Main(Class("max-w-2xl mx-auto p-8 space-y-6"),
+			H1(Class("text-2xl font-bold"), T("Import a Thread")),
+			P(Class("text-gray-600"),
+				T("Upload a session exported as JSON, or paste one below. Only prompt and subreddit are required; stances and comments are optional."),
+			),
+			Form(Method("POST"), Action("/import"), Attr("enctype", "multipart/form-data"),
+				Div(Class("mb-4"),
+					Label(For("file"), Class("block font-medium mb-1"), T("Session JSON file")),
+					Input(Type("file"), Id("file"), Name("file"), Attr("accept", ".json,application/json"), Class("file-input w-full")),
+				),
+				Div(Class("mb-4"),
+					Label(For("json"), Class("block font-medium mb-1"), T("…or paste JSON")),
+					TextArea(Id("json"), Name("json"), Class("w-full border rounded p-2 font-mono text-sm"), Rows(10),
+						Placeholder(`{"prompt": "...", "subreddit": "aita", "comments": [...]}`)),
+				),
+				Div(Class("mb-4"),
+					Label(Class("flex items-center gap-2"),
+						Input(Type("checkbox"), Name("continue"), Value("1"), Class("checkbox")),
+						T("Continue generating where the thread left off"),
+					),
+				),
+				Button(Type("submit"), Class("bg-blue-600 text-white px-4 py-2 rounded"), T("Import")),
+			),
+		),
+	)
+}
+
+// importHandler recreates a RedditSession from an uploaded document and opens it on /session
+func importHandler(client *openai.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			ServeNode(ImportPage())(w, r)
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes)
+		if err := r.ParseMultipartForm(importMaxBytes); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		data := []byte(r.FormValue("json"))
+		if file, _, err := r.FormFile("file"); err == nil {
+			data, err = io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				http.Error(w, "Error reading upload", http.StatusBadRequest)
+				return
+			}
+		}
+		if len(strings.TrimSpace(string(data))) == 0 {
+			http.Error(w, "Upload a file or paste session JSON", http.StatusBadRequest)
+			return
+		}
+
+		continueGeneration := r.FormValue("continue") != ""
+		session, err := ImportSession(data, CurrentUser(r), continueGeneration)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("[INFO] Imported session %s", session.ID)
+
+		if continueGeneration {
+			go runSimulation(client, session)
+		}
+		http.Redirect(w, r, "/session?id="+session.ID, http.StatusSeeOther)
+	}
+}
+
+// checkImportedComments replaces each comment's stance with the known one and
+// rejects unknown stances and verdict codes, all the way down the reply tree
+func checkImportedComments(comments []SimulatedComment) error {
+	for i := range comments {
+		c := &comments[i]
+		if c.Stance != nil {
+			known, ok := LookupStance(c.Stance.Type, c.Stance.SubType)
+			if !ok {
+				return fmt.Errorf("unknown stance %s/%s", c.Stance.Type, c.Stance.SubType)
+			}
+			c.Stance = &known
+		}
+		if c.Verdict != "" {
+			if _, ok := LookupVerdict(c.Verdict); !ok {
+				return fmt.Errorf("unknown verdict %q", c.Verdict)
+			}
+		}
+		if err := checkImportedComments(c.Replies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportSession parses an exported session document into a new session owned by owner.
+// Unless it is going to be continued, the imported session is marked done.
+func ImportSession(data []byte, owner string, continueGeneration bool) (*RedditSession, error) {
+	var doc apiSimulation
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid session JSON: %v", err)
+	}
+	if strings.TrimSpace(doc.Prompt) == "" {
+		return nil, fmt.Errorf("session JSON needs a prompt")
+	}
+	if _, ok := LookupSubreddit(doc.Subreddit); !ok {
+		return nil, fmt.Errorf("unknown subreddit %q", doc.Subreddit)
+	}
+
+	// Stances and verdicts end up in page markup, so only known ones are let in.
+	// This also fills in summaries that hand-written stances leave out.
+	stances, err := resolveStances(doc.SelectedStances)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImportedComments(doc.Responses); err != nil {
+		return nil, err
+	}
+	// Without a stance list, fall back to the stances the comments were written from
+	if len(stances) == 0 {
+		for _, c := range doc.Responses {
+			if c.Stance != nil {
+				stances = append(stances, *c.Stance)
+			}
+		}
+	}
+
+	session := NewSession(doc.Prompt, doc.Subreddit, owner)
+	session.mu.Lock()
+	session.Model = doc.Model
+	if doc.Seed != 0 {
+		// Keep the original seed so continuing an export makes the same choices
+		session.Seed = doc.Seed
+	}
+	session.SelectedStances = stances
+	session.Responses = doc.Responses
+	session.Summary = doc.Summary
+	if continueGeneration {
+		// The thread is about to change, so the old summary no longer applies
+		session.Summary = nil
+	} else {
+		session.setState(StateDone)
+	}
+	session.mu.Unlock()
+	return session, nil
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	crand "crypto/rand"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,45 +34,119 @@ type StanceSelectionResponse struct {
 	Stances []Stance `json:"stances"`
 }
 
-// Each user gets a RedditSession
+// Each user gets a RedditSession. ID, Prompt, Subreddit, Owner and CreatedAt never
+// change once NewSession returns; every other field is guarded by mu.
 type RedditSession struct {
-	ID              string
-	Prompt          string
-	Subreddit       string
-	SelectedStances []Stance // The stances chosen by GPT
-	Responses       []SimulatedComment
-	Done            bool
-	Error           error
+	ID              string              `json:"id"`
+	Prompt          string              `json:"prompt"`
+	Subreddit       string              `json:"subreddit"`
+	Model           string              `json:"model,omitempty"`     // Empty means the default models
+	ParentID        string              `json:"parent_id,omitempty"` // The session this one was forked from
+	Owner           string              `json:"owner,omitempty"`     // Username of the account that created it; empty for anonymous sessions
+	CreatedAt       time.Time           `json:"created_at"`
+	SelectedStances []Stance            `json:"stances"` // The stances chosen by GPT
+	Responses       []SimulatedComment  `json:"comments"`
+	Summary         *ThreadSummary      `json:"summary,omitempty"` // Filled in after all replies are generated
+	Seed            int64               `json:"seed"`              // Fixes every random choice of the run; same seed + deterministic model = same thread
+	State           SessionState        `json:"state"`
+	Transitions     []StateTransition   `json:"transitions"`        // When each state was entered
+	Failures        []GenerationFailure `json:"failures,omitempty"` // Steps that failed, shown to the user as notices
+	Shares          []ShareLink         `json:"-"`                  // Read-only links handed out by the owner
+
+	// rng drives every random choice of the simulation; nil means one seeded from Seed.
+	// Tests and tools can inject their own before runSimulation.
+	rng *rand.Rand
+
+	deleted bool // Set once the session is removed from the store
+
+	Events  []SessionEvent `json:"-"` // Everything sent to live viewers, in order
+	logged  eventCursor    // How far publish has got
+	changed chan struct{}  // Closed by publish when Events grows
+	viewers int            // Live /ws and /events streams, for the presence indicator
+
+	mu sync.Mutex
 }
 
 // Comment-style response from a Reddit simulation
 type SimulatedComment struct {
-	Username string
-	Flair    string
-	Text     string
-	Replies  []SimulatedComment
+	Username string             `json:"username"`
+	Flair    string             `json:"flair"`
+	Text     string             `json:"text"`
+	Stance   *Stance            `json:"stance,omitempty"`  // The stance a top-level comment was written from; nil for replies
+	Verdict  string             `json:"verdict,omitempty"` // AITA verdict code (YTA, NTA, ...); empty outside aita mode
+	Score    int                `json:"score,omitempty"`   // Simulated upvotes, used to weigh verdicts
+	Replies  []SimulatedComment `json:"replies,omitempty"`
 }
 
-// Session store (in-memory for now)
+// A subreddit the simulation knows how to imitate
+type Subreddit struct {
+	Name  string
+	Label string
+}
+
+// Subreddits offered on the prompt page and accepted by the API
+var Subreddits = []Subreddit{
+	{Name: "aita", Label: "r/AmITheAsshole"},
+	{Name: "relationships", Label: "r/relationships"},
+	{Name: "legaladvice", Label: "r/legaladvice"},
+	{Name: "askreddit", Label: "r/AskReddit"},
+}
+
+// Session store (in-memory for now). sessionsMutex guards the map only; each
+// session has its own lock. Take sessionsMutex first when both are needed.
 var (
 	sessions      = make(map[string]*RedditSession)
 	sessionsMutex sync.Mutex
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true }, // For local dev
+// /ws streams owner-only sessions to whoever holds the login cookie, so other
+// sites' pages must not open it (cross-site WebSocket hijacking)
+var upgrader = websocket.Upgrader{CheckOrigin: sameOrigin}
+
+// sameOrigin accepts WebSocket handshakes from siteURL or the host being asked,
+// and from non-browser clients, which send no Origin
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || strings.EqualFold(origin, siteURL) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
 }
 
 // ---------- MAIN + ROUTES ----------
 
 func main() {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("OPENAI_API_KEY not set")
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		fmt.Print(cliUsage)
+		return
+	}
+
+	// Anything other than "serve" is a headless CLI command. Commands ask for the
+	// client only after parsing their flags, so "simulate -h" works without a key.
+	if len(args) > 0 && args[0] != "serve" {
+		os.Exit(runCommand(args))
 	}
-	client := openai.NewClient(apiKey)
 
-	http.HandleFunc("/", ServeNode(RedditHomePage()))
+	client, err := newOpenAIClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	registerAPIRoutes(client)
+	registerAccountRoutes()
+	registerShareRoutes()
+
+	policy, err := retentionFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	startJanitor(policy)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		ServeNode(RedditHomePage(CurrentUser(r)))(w, r)
+	})
 	http.HandleFunc("/new", ServeNode(RedditPromptPage()))
 
 	http.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
@@ -85,108 +164,53 @@ func main() {
 			http.Error(w, "Prompt cannot be empty", http.StatusBadRequest)
 			return
 		}
+		if _, ok := LookupSubreddit(subreddit); !ok {
+			http.Error(w, "Unknown subreddit", http.StatusBadRequest)
+			return
+		}
+		seedText := strings.TrimSpace(r.FormValue("seed"))
+		seed, err := strconv.ParseInt(seedText, 10, 64)
+		if seedText != "" && err != nil {
+			http.Error(w, "Seed must be a whole number", http.StatusBadRequest)
+			return
+		}
 
 		// Create and store the session
-		session := NewSession(prompt, subreddit)
+		session := NewSession(prompt, subreddit, CurrentUser(r))
+		if seedText != "" {
+			session.mu.Lock()
+			session.Seed = seed
+			session.mu.Unlock()
+		}
 		log.Printf("[INFO] Created session %s", session.ID)
 
 		// Kick off AI work in background goroutine
-		go func(sess *RedditSession) {
-			var wg sync.WaitGroup
-
-			// 1) Get stances from GPT
-			selectedStances, err := generateStances(client, subreddit, prompt)
-			if err != nil {
-				log.Printf("[ERROR] generating stances: %v", err)
-				sess.Error = err
-				sess.Done = true
-				return
-			}
-
-			// 2) Store stances in the session
-			sessionsMutex.Lock()
-			sess.SelectedStances = selectedStances
-			sessionsMutex.Unlock()
-
-			// 3) For each stance, generate a single top-level comment
-			for _, stance := range selectedStances {
-				text, err := GenerateResponseFromStance(client, prompt, stance)
-				if err != nil {
-					log.Printf("[ERROR] generating response: %v", err)
-					sess.Error = err
-					break
-				}
-
-				// Build the top-level comment
-				comment := SimulatedComment{
-					Username: fmt.Sprintf("%s_%s", stance.Type, stance.SubType),
-					Flair:    stance.Type,
-					Text:     text,
-				}
-
-				// Append to session and get its index
-				sessionsMutex.Lock()
-				idx := len(sess.Responses)
-				sess.Responses = append(sess.Responses, comment)
-				sessionsMutex.Unlock()
-
-				// Spawn a goroutine to generate a reply for THIS top-level comment
-				wg.Add(1)
-				go func(parentIndex int, parentText string) {
-					defer wg.Done()
-
-					replyText, err := GenerateReplyToComment(client, sess.Prompt, parentText)
-					if err != nil {
-						log.Printf("[ERROR] generating reply: %v", err)
-						// We'll just log the error. We won't stop the entire session.
-						return
-					}
-
-					child := SimulatedComment{
-						Username: randomReplyUsername(),
-						Flair:    "reply",
-						Text:     replyText,
-					}
-
-					sessionsMutex.Lock()
-					sess.Responses[parentIndex].Replies = append(sess.Responses[parentIndex].Replies, child)
-					sessionsMutex.Unlock()
-				}(idx, text)
-			}
-
-			// 4) Once ALL replies are done, mark the session done
-			go func() {
-				wg.Wait()
-				sess.Done = true
-			}()
-		}(session)
+		go runSimulation(client, session)
 
 		http.Redirect(w, r, "/session?id="+session.ID, http.StatusSeeOther)
 	})
 
 	http.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
-		id := r.URL.Query().Get("id")
-		if id == "" {
-			http.Error(w, "Missing session ID", http.StatusBadRequest)
-			return
-		}
-		session, ok := GetSession(id)
+		session, ok := authorizeSession(w, r, r.URL.Query().Get("id"))
 		if !ok {
-			http.Error(w, "Invalid session ID", http.StatusNotFound)
 			return
 		}
-		ServeNode(RedditSessionPage(session.Prompt, session.ID))(w, r)
+		ServeNode(RedditSessionPage(session, CurrentUser(r)))(w, r)
 	})
 
+	http.HandleFunc("/delete", deleteHandler)
+	http.HandleFunc("/retry", retryHandler(client))
+	http.HandleFunc("/reply", opReplyHandler)
+	http.HandleFunc("/export", exportHandler)
+	http.HandleFunc("/import", importHandler(client))
+	http.HandleFunc("/fork", forkHandler(client))
+	http.HandleFunc("/compare", comparePageHandler)
+	http.HandleFunc("/history", historyPageHandler)
+
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		id := r.URL.Query().Get("id")
-		if id == "" {
-			http.Error(w, "Missing session ID", http.StatusBadRequest)
-			return
-		}
-		sess, ok := GetSession(id)
+		sess, ok := authorizeSession(w, r, id)
 		if !ok {
-			http.Error(w, "Invalid session", http.StatusNotFound)
 			return
 		}
 
@@ -199,70 +223,51 @@ func main() {
 
 		log.Printf("WebSocket connected for session %s", id)
 
-		lastSentTopLevel := 0
-		replyCounts := make([]int, 0)
-
-		// In your loop setup, you might do:
-		sessionsMutex.Lock()
-		replyCounts = make([]int, len(sess.Responses))
-		sessionsMutex.Unlock()
-
-		for {
-			sessionsMutex.Lock()
-			done := sess.Done
-
-			// 1) Check if any new top-level comments arrived
-			for lastSentTopLevel < len(sess.Responses) {
-				comment := sess.Responses[lastSentTopLevel]
-				html := RenderCommentRecursive(comment, 0).Render()
-				fmt.Println("Rendering comment:", html)
-				conn.WriteJSON(map[string]string{
-					"type":        "comment",
-					"parentIndex": fmt.Sprintf("%d", lastSentTopLevel),
-					"html":        html,
-				})
-				lastSentTopLevel++
-				replyCounts = append(replyCounts, len(comment.Replies))
-			}
-
-			// 2) Check each existing comment for new replies
-			for i, comment := range sess.Responses {
-				newReplyCount := len(comment.Replies)
-				if newReplyCount > replyCounts[i] {
-					// We have new replies
-					for r := replyCounts[i]; r < newReplyCount; r++ {
-						singleReply := comment.Replies[r]
-						replyHTML := RenderCommentRecursive(singleReply, 1).Render()
-						// We'll also send info about which parent index or comment ID to attach to
-						conn.WriteJSON(map[string]string{
-							"type":        "reply",
-							"parentIndex": fmt.Sprintf("%d", i),
-							"html":        replyHTML,
-						})
-					}
-					replyCounts[i] = newReplyCount
+		// The client never sends anything, but reading is how a closed socket shows up
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
 				}
 			}
-
-			sessionsMutex.Unlock()
-
-			if done {
-				conn.WriteJSON(map[string]string{"type": "done"})
-				return
+		}()
+
+		// A reconnecting client passes the last seq it saw and only gets what it missed
+		since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+		withHTML := wantsHTML(r)
+		err = streamEvents(ctx, sess, since, func(e SessionEvent) error {
+			if !withHTML {
+				e.HTML = ""
 			}
-			time.Sleep(500 * time.Millisecond)
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			return conn.WriteJSON(e)
+		}, func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+		})
+		if err == nil {
+			// The session was deleted: say goodbye properly so the client doesn't see a drop
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session deleted"),
+				time.Now().Add(streamWriteTimeout))
+		} else if ctx.Err() == nil {
+			log.Printf("WebSocket for session %s dropped: %v", id, err)
 		}
 	})
+	http.HandleFunc("/events", sseHandler)
+	http.Handle("/static/", staticHandler())
 
 	log.Println("[INFO] Listening on http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Fatal(http.ListenAndServe(":8080", withCurrentUser(http.DefaultServeMux)))
 }
 
 // ---------- LAYOUT / TEMPLATES ----------
 
 // Home page
-func RedditHomePage() *Node {
+func RedditHomePage(user string) *Node {
 	return DefaultLayout(
+		Div(Class("container mx-auto px-8 pt-4 flex justify-end"), AccountNav(user)),
 		Div(Class("container mx-auto p-8 text-center space-y-4"),
 			H1(Class("text-3xl font-bold"), T("Welcome to the Reddit Simulation Tool")),
 			P(Class("text-lg"),
@@ -272,6 +277,14 @@ func RedditHomePage() *Node {
 				Class("inline-block mt-4 text-blue-600 hover:underline"),
 				T("Start a New Post"),
 			),
+			A(Href("/history"),
+				Class("block text-sm text-gray-500 hover:underline"),
+				T("Browse past sessions"),
+			),
+			A(Href("/import"),
+				Class("block text-sm text-gray-500 hover:underline"),
+				T("Import a saved thread"),
+			),
 		),
 		Footer(
 			Class("text-center text-sm text-gray-500"),
@@ -292,12 +305,12 @@ func RedditPromptPage() *Node {
 				),
 				Div(Class("mb-4"),
 					Label(For("subreddit"), Class("block font-medium mb-1"), T("Simulated Subreddit")),
-					Select(Name("subreddit"), Id("subreddit"), Class("w-full border rounded p-2"),
-						Option(Value("aita"), T("r/AmITheAsshole")),
-						Option(Value("relationships"), T("r/relationships")),
-						Option(Value("legaladvice"), T("r/legaladvice")),
-						Option(Value("askreddit"), T("r/AskReddit")),
-					),
+					SubredditSelect(""),
+				),
+				Div(Class("mb-4"),
+					Label(For("seed"), Class("block font-medium mb-1"), T("Seed (optional)")),
+					Input(Type("number"), Id("seed"), Name("seed"), Placeholder("Random"), Class("w-full border rounded p-2")),
+					P(Class("text-xs text-gray-500 mt-1"), T("Reuse the seed of an earlier session to get the same thread again.")),
 				),
 				Button(Type("submit"), Class("bg-blue-600 text-white px-4 py-2 rounded"), T("Simulate Responses")),
 			),
@@ -305,6 +318,16 @@ func RedditPromptPage() *Node {
 	)
 }
 
+// SubredditSelect renders the subreddit dropdown with 'selected' preselected
+func SubredditSelect(selected string) *Node {
+	sel := Select(Name("subreddit"), Id("subreddit"), Class("w-full border rounded p-2"))
+	for _, sub := range Subreddits {
+		opt := Option(Value(sub.Name), Selected(sub.Name == selected), Text(sub.Label))
+		sel.Children = append(sel.Children, opt)
+	}
+	return sel
+}
+
 // RenderCommentRecursive renders a single comment, then any child replies.
 // 'indentLevel' tells us how far to indent for nested replies.
 func RenderCommentRecursive(c SimulatedComment, indentLevel int) *Node {
@@ -313,8 +336,14 @@ func RenderCommentRecursive(c SimulatedComment, indentLevel int) *Node {
 	// Render this comment
 	mainComment := Div(Class(fmt.Sprintf("bg-white p-4 rounded shadow mb-4 %s", indentClass)),
 		Div(Class("flex items-center justify-between"),
-			Span(Class("font-semibold text-blue-700"), Text(c.Username)),
-			Span(Class("text-sm text-gray-500"), Text(c.Flair)),
+			Div(Class("flex items-center gap-2"),
+				Span(Class("font-semibold text-blue-700"), Text(c.Username)),
+				If(c.Verdict != "", VerdictBadge(c.Verdict), Nil()),
+			),
+			Div(Class("flex items-center gap-2 text-sm text-gray-500"),
+				If(c.Score > 0, Span(Text(fmt.Sprintf("↑ %d", c.Score))), Nil()),
+				Span(Text(c.Flair)),
+			),
 		),
 		P(Class("mt-2 text-gray-800"), Text(c.Text)),
 	)
@@ -336,72 +365,84 @@ func RenderCommentRecursive(c SimulatedComment, indentLevel int) *Node {
 	return Div(replyNodes...)
 }
 
+// What static/session.js needs to know about the page's session
+type sessionConfig struct {
+	ID string `json:"id"`
+}
+
 // Page that displays the simulated responses
-func RedditSessionPage(prompt string, sessionID string) *Node {
+func RedditSessionPage(session *RedditSession, viewer string) *Node {
+	prompt, sessionID, subreddit := session.Prompt, session.ID, session.Subreddit
+	var forkIDs []string
+	for _, f := range sessionForks(session.ID, viewer) {
+		forkIDs = append(forkIDs, f.ID)
+	}
+
+	session.mu.Lock()
+	model, parentID, seed := session.Model, session.ParentID, session.Seed
+	shares := append([]ShareLink(nil), session.Shares...)
+	failures := FailureNotices(session)
+	session.mu.Unlock()
+
 	return DefaultLayout(
 		Div(Class("max-w-2xl mx-auto p-6 space-y-6"),
-			H1(Class("text-2xl font-bold"), T("Your Reddit Simulation")),
+			Div(Class("flex items-center justify-between"),
+				H1(Class("text-2xl font-bold"), T("Your Reddit Simulation")),
+				Span(Id("presence"), Class("badge badge-ghost hidden"), Attr("aria-live", "polite")),
+			),
+			LineageLinks(sessionID, parentID, forkIDs),
+			failures,
+			Div(Id("judgmentArea")),
+			Div(Id("summaryArea")),
+			Div(Id("stanceArea")),
 			Div(Class("bg-gray-100 p-4 rounded"),
 				H2(Class("font-semibold text-lg"), T("Your Post")),
 				P(Class("mt-2 whitespace-pre-wrap text-gray-800"), Text(prompt)),
 			),
+			ExportLinks(sessionID),
+			P(Class("text-xs text-gray-500"), Text(fmt.Sprintf("Seed %d — start a new post with it to regenerate this thread.", seed))),
+			ForkForm(sessionID, subreddit, model),
+			SharePanel(sessionID, shares),
+			DeleteButton(sessionID),
 			Div(Id("responseArea"),
-				P(Class("text-gray-500 italic"), T("Generating simulated responses...")),
-				Div(Class("mt-2"),
-					Progress(Class("progress progress-primary w-full"), Max("100")),
+				Div(Id("generatingNotice"),
+					P(Class("text-gray-500 italic"), T("Generating simulated responses...")),
+					Div(Class("mt-2"),
+						Progress(Class("progress progress-primary w-full"), Max("100")),
+					),
 				),
 			),
-			Script(Raw(fmt.Sprintf(`
-	let ws = new WebSocket("ws://" + window.location.host + "/ws?id=%s");
-	let responseArea = document.getElementById("responseArea");
-
-	ws.onmessage = function(event) {
-		let data = JSON.parse(event.data);
-
-		if (data.type === "comment") {
-			// Create a container for this top-level comment
-			let parentDiv = document.createElement("div");
-			parentDiv.setAttribute("id", "comment-" + data.parentIndex);
-			parentDiv.innerHTML = data.html;
-			responseArea.appendChild(parentDiv);
-
-		} else if (data.type === "reply") {
-			// Append a reply to an existing comment's container
-			let parentDiv = document.getElementById("comment-" + data.parentIndex);
-			if (!parentDiv) {
-				console.warn("No parent container found for index", data.parentIndex);
-				return;
-			}
-			let replyDiv = document.createElement("div");
-			replyDiv.innerHTML = data.html;
-			parentDiv.appendChild(replyDiv);
-
-		} else if (data.type === "done") {
-			// Signal that simulation is complete
-			let p = document.createElement("p");
-			p.innerText = "Simulation complete.";
-			responseArea.appendChild(p);
-			ws.close();
-		}
-	};
-`, sessionID))),
+			OPReplyForm(),
+			JSONScript("sessionConfig", sessionConfig{ID: sessionID}),
+			Script(Src(staticURL("session.js"))),
 		),
 	)
 }
 
 // ---------- HELPER FUNCTIONS ----------
 
-// Creates a new session
-func NewSession(prompt, subreddit string) *RedditSession {
-	id := randomID()
+// Creates a new session; owner is "" for anonymous sessions
+func NewSession(prompt, subreddit, owner string) *RedditSession {
+	now := time.Now()
 	s := &RedditSession{
-		ID:        id,
-		Prompt:    prompt,
-		Subreddit: subreddit,
+		Prompt:      prompt,
+		Subreddit:   subreddit,
+		Owner:       owner,
+		CreatedAt:   now,
+		Seed:        randomSeed(),
+		State:       StatePending,
+		Transitions: []StateTransition{{State: StatePending, At: now}},
 	}
 	sessionsMutex.Lock()
-	sessions[id] = s
-	sessionsMutex.Unlock()
+	defer sessionsMutex.Unlock()
+	// Collisions are astronomically unlikely, but an ID must never hand over someone else's session
+	for {
+		s.ID = randomID()
+		if _, taken := sessions[s.ID]; !taken {
+			break
+		}
+	}
+	sessions[s.ID] = s
 	return s
 }
 
@@ -413,18 +454,93 @@ func GetSession(id string) (*RedditSession, bool) {
 	return s, ok
 }
 
-// Simple random ID generator (12-char)
+// allSessions lists every session in the store, in no particular order
+func allSessions() []*RedditSession {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	list := make([]*RedditSession, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	return list
+}
+
+// snapshot copies the session's persisted fields, e.g. for encoding; callers hold s.mu
+func (s *RedditSession) snapshot() *RedditSession {
+	return &RedditSession{
+		ID:              s.ID,
+		Prompt:          s.Prompt,
+		Subreddit:       s.Subreddit,
+		Model:           s.Model,
+		ParentID:        s.ParentID,
+		Owner:           s.Owner,
+		CreatedAt:       s.CreatedAt,
+		SelectedStances: s.SelectedStances,
+		Responses:       s.Responses,
+		Summary:         s.Summary,
+		Seed:            s.Seed,
+		State:           s.State,
+		Transitions:     s.Transitions,
+		Failures:        s.Failures,
+	}
+}
+
+// countComments counts comments and all their nested replies
+func countComments(comments []SimulatedComment) int {
+	n := len(comments)
+	for _, c := range comments {
+		n += countComments(c.Replies)
+	}
+	return n
+}
+
+// firstLine returns the first non-empty line of a post, for listings
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// Finds a subreddit by its short name, e.g. "aita"
+func LookupSubreddit(name string) (Subreddit, bool) {
+	for _, sub := range Subreddits {
+		if sub.Name == name {
+			return sub, true
+		}
+	}
+	return Subreddit{}, false
+}
+
+// randomID returns an unguessable 16-char alphanumeric session ID (~95 bits) from crypto/rand
 func randomID() string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	rand.Seed(time.Now().UnixNano())
-	b := make([]byte, 12)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+	// Bytes >= 248 are rejected so every letter is equally likely (248 = 4*62)
+	const limit = 256 - 256%len(letters)
+	b := make([]byte, 0, 16)
+	buf := make([]byte, 32)
+	for len(b) < cap(b) {
+		crand.Read(buf)
+		for _, c := range buf {
+			if int(c) < limit && len(b) < cap(b) {
+				b = append(b, letters[int(c)%len(letters)])
+			}
+		}
 	}
 	return string(b)
 }
 
-func randomReplyUsername() string {
+// randomSeed returns an unpredictable seed for a simulation's RNG.
+// It stays below 2^53 so JavaScript clients can round-trip it through JSON.
+func randomSeed() int64 {
+	var b [8]byte
+	crand.Read(b[:])
+	return int64(binary.LittleEndian.Uint64(b[:]) >> 11)
+}
+
+func randomReplyUsername(rng *rand.Rand) string {
 	names := []string{
 		"ReplyMaster",
 		"CuriousCat",
@@ -437,13 +553,22 @@ func randomReplyUsername() string {
 		"SkepticalSam",
 		"AgreeableAlex",
 	}
-	return names[rand.Intn(len(names))]
+	return names[rng.Intn(len(names))]
 }
 
 // ---------- AI FUNCTIONS ----------
 
+// newOpenAIClient builds the client every generation step uses from OPENAI_API_KEY
+func newOpenAIClient() (*openai.Client, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY not set")
+	}
+	return openai.NewClient(apiKey), nil
+}
+
 // generateStances picks 5-8 stances from AllStances using GPT's function-calling
-func generateStances(client *openai.Client, thread string, post string) ([]Stance, error) {
+func generateStances(client *openai.Client, model string, seed *int, thread string, post string) ([]Stance, error) {
 	// Create a JSON-safe string version of AllStances to pass to GPT
 	allStancesJSON, err := json.Marshal(AllStances)
 	if err != nil {
@@ -490,7 +615,8 @@ Here is the full list of allowed stances (with type, subtype, and summary):
 	}
 
 	chatRequest := openai.ChatCompletionRequest{
-		Model: "gpt-4-0613",
+		Model: modelOr(model, "gpt-4-0613"),
+		Seed:  seed,
 		Messages: []openai.ChatCompletionMessage{
 			systemPrompt,
 			userMessage,
@@ -519,7 +645,7 @@ Here is the full list of allowed stances (with type, subtype, and summary):
 }
 
 // GenerateResponseFromStance creates a single Reddit comment from a stance + user prompt
-func GenerateResponseFromStance(client *openai.Client, prompt string, stance Stance) (string, error) {
+func GenerateResponseFromStance(client *openai.Client, model string, seed *int, prompt string, stance Stance) (string, error) {
 	systemMsg := openai.ChatCompletionMessage{
 		Role: openai.ChatMessageRoleSystem,
 		Content: fmt.Sprintf(
@@ -543,7 +669,8 @@ Your response should sound like a typical Reddit user with that viewpoint.
 	resp, err := client.CreateChatCompletion(
 		context.Background(),
 		openai.ChatCompletionRequest{
-			Model:    openai.GPT4,
+			Model:    modelOr(model, openai.GPT4),
+			Seed:     seed,
 			Messages: []openai.ChatCompletionMessage{systemMsg, userMsg},
 		},
 	)
@@ -558,8 +685,7 @@ Your response should sound like a typical Reddit user with that viewpoint.
 	return resp.Choices[0].Message.Content, nil
 }
 
-func GenerateReplyToComment(client *openai.Client, originalPost, parentComment string) (string, error) {
-	fmt.Println("Generating reply to comment")
+func GenerateReplyToComment(client *openai.Client, model string, seed *int, originalPost, parentComment string) (string, error) {
 	systemMsg := openai.ChatCompletionMessage{
 		Role: openai.ChatMessageRoleSystem,
 		Content: `You are simulating a reply in a Reddit thread. 
@@ -580,7 +706,8 @@ Please write a single short reply to the parent comment.`, originalPost, parentC
 	}
 
 	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
-		Model:    openai.GPT4,
+		Model:    modelOr(model, openai.GPT4),
+		Seed:     seed,
 		Messages: []openai.ChatCompletionMessage{systemMsg, userMsg},
 	})
 	if err != nil {
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------- MARKDOWN RENDERING ----------
+
+// RenderMarkdown renders a session as Markdown. Replies are nested one
+// blockquote level deeper than their parent comment.
+func RenderMarkdown(s *RedditSession) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b strings.Builder
+
+	title := s.Subreddit
+	if sub, ok := LookupSubreddit(s.Subreddit); ok {
+		title = sub.Label
+	}
+	fmt.Fprintf(&b, "# %s simulation\n\n", title)
+	writeMarkdownQuote(&b, "> ", s.Prompt)
+	b.WriteString("\n")
+
+	if s.Subreddit == "aita" {
+		if j, ok := TallyVerdicts(s.Responses); ok {
+			fmt.Fprintf(&b, "**Reddit's judgment: %s — %s**", j.Winner.Code, j.Winner.Label)
+			var parts []string
+			for _, t := range j.Tally {
+				parts = append(parts, fmt.Sprintf("%s %d%%", t.Verdict.Code, t.Percent))
+			}
+			fmt.Fprintf(&b, " (%s)\n\n", strings.Join(parts, ", "))
+		}
+	}
+
+	if s.Summary != nil {
+		b.WriteString("## What the thread thinks\n\n")
+		writeMarkdownList(&b, "Key themes", s.Summary.Themes)
+		writeMarkdownList(&b, "Where people agreed", s.Summary.Consensus)
+		writeMarkdownList(&b, "Where people disagreed", s.Summary.Conflicts)
+		writeMarkdownList(&b, "Blind spots the thread raised", s.Summary.BlindSpots)
+		writeMarkdownList(&b, "Questions to reflect on", s.Summary.ReflectionQuestions)
+	}
+
+	b.WriteString("## Comments\n\n")
+	for _, c := range s.Responses {
+		writeMarkdownComment(&b, c, 0)
+		b.WriteString("---\n\n")
+	}
+	return b.String()
+}
+
+func writeMarkdownComment(b *strings.Builder, c SimulatedComment, depth int) {
+	prefix := strings.Repeat("> ", depth)
+
+	header := fmt.Sprintf("**%s** · _%s_", c.Username, c.Flair)
+	if c.Verdict != "" {
+		header += fmt.Sprintf(" · `%s`", c.Verdict)
+	}
+	if c.Score > 0 {
+		header += fmt.Sprintf(" · ↑ %d", c.Score)
+	}
+	writeMarkdownQuote(b, prefix, header)
+	writeMarkdownQuote(b, prefix, "")
+	writeMarkdownQuote(b, prefix, c.Text)
+	b.WriteString("\n")
+
+	for _, r := range c.Replies {
+		writeMarkdownComment(b, r, depth+1)
+	}
+}
+
+// writeMarkdownQuote writes every line of text behind the given quote prefix
+func writeMarkdownQuote(b *strings.Builder, prefix, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(strings.TrimRight(prefix+line, " "))
+		b.WriteString("\n")
+	}
+}
+
+func writeMarkdownList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "**%s**\n\n", title)
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+)
+
+// ---------- EVENT PROTOCOL ----------
+
+// ProtocolVersion is sent as "v" with every event on /ws and /events. It changes only
+// for breaking changes; new event types and optional fields keep the same version.
+// docs/events.schema.json describes every message.
+const ProtocolVersion = 1
+
+// Event types. Comments and replies are sent whole once generated; there are no
+// partial-text (delta) events.
+const (
+	EventStances      = "stances"      // The stance mix planned for the thread
+	EventDistribution = "distribution" // The stance split of the comments posted so far; follows each new comment
+	EventComment      = "comment"
+	EventReply        = "reply"
+	EventSummary      = "summary"
+	EventJudgment     = "judgment"
+	EventDone         = "done"
+	EventError        = "error"
+	EventRetrying     = "retrying" // A retry started; earlier errors no longer apply and a new "done" will follow
+	EventPresence     = "presence" // How many people are watching; not part of the log, so it never counts toward since
+	EventDeleted      = "deleted"  // The session is gone for good; always the last event, after which the stream ends
+)
+
+// SessionEvent is one update to a session, in the order it happened. Seq starts at 1
+// and has no gaps, so a client that saw event N can resume with ?since=N.
+// Presence events are the exception: they repeat the seq of the last logged event.
+// Which payload field is set depends on Type.
+type SessionEvent struct {
+	V        int            `json:"v"`
+	Seq      int            `json:"seq"`
+	Type     string         `json:"type"`
+	Stances  []Stance       `json:"stances,omitempty"`  // stances, distribution
+	Comment  *CommentData   `json:"comment,omitempty"`  // comment, reply
+	Summary  *ThreadSummary `json:"summary,omitempty"`  // summary
+	Judgment *JudgmentData  `json:"judgment,omitempty"` // judgment
+	Error    *ErrorData     `json:"error,omitempty"`    // error
+	Status   string         `json:"status,omitempty"`   // done: "done", "failed" or "cancelled"
+	Viewers  int            `json:"viewers,omitempty"`  // presence
+	HTML     string         `json:"html,omitempty"`     // Fragment for the session page; left out with ?html=0
+}
+
+// CommentData is a top-level comment or a reply
+type CommentData struct {
+	ID       string  `json:"id"`                  // "c2" for the third top-level comment, "c2-r0" for its first reply
+	ParentID string  `json:"parent_id,omitempty"` // Set on replies
+	Author   string  `json:"author"`
+	Flair    string  `json:"flair,omitempty"`
+	Stance   *Stance `json:"stance,omitempty"`
+	Text     string  `json:"text"`
+	Score    int     `json:"score"`
+	Verdict  string  `json:"verdict,omitempty"`
+}
+
+// ErrorData reports a failed generation step. Fatal errors stopped the thread;
+// others (a single reply, the summary) leave it otherwise intact.
+type ErrorData struct {
+	ID        string `json:"id"` // "f0", "f1", ... in the order failures happened
+	Stage     string `json:"stage"`
+	CommentID string `json:"comment_id,omitempty"` // The comment a failed reply belongs under
+	Message   string `json:"message"`              // Safe to show to users
+	Fatal     bool   `json:"fatal"`
+	Retryable bool   `json:"retryable"` // POST /api/v1/simulations/{id}/retry can pick up from here
+}
+
+// JudgmentData is the AITA outcome of a finished thread
+type JudgmentData struct {
+	Winner string              `json:"winner"` // Verdict code, e.g. "NTA"
+	Label  string              `json:"label"`
+	Total  int                 `json:"total"`
+	Tally  []JudgmentTallyData `json:"tally"`
+}
+
+type JudgmentTallyData struct {
+	Verdict string `json:"verdict"`
+	Weight  int    `json:"weight"`
+	Percent int    `json:"percent"`
+}
+
+// commentID identifies a top-level comment by its position, which never changes
+func commentID(index int) string {
+	return fmt.Sprintf("c%d", index)
+}
+
+func replyID(parentIndex, index int) string {
+	return fmt.Sprintf("%s-r%d", commentID(parentIndex), index)
+}
+
+func newCommentData(c SimulatedComment, id, parentID string) *CommentData {
+	return &CommentData{
+		ID:       id,
+		ParentID: parentID,
+		Author:   c.Username,
+		Flair:    c.Flair,
+		Stance:   c.Stance,
+		Text:     c.Text,
+		Score:    c.Score,
+		Verdict:  c.Verdict,
+	}
+}
+
+func newJudgmentData(j Judgment) *JudgmentData {
+	data := &JudgmentData{Winner: j.Winner.Code, Label: j.Winner.Label, Total: j.Total}
+	for _, t := range j.Tally {
+		data.Tally = append(data.Tally, JudgmentTallyData{Verdict: t.Verdict.Code, Weight: t.Weight, Percent: t.Percent})
+	}
+	return data
+}
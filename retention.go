@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ---------- RETENTION ----------
+
+// RetentionPolicy bounds how long and how many sessions stay in memory; zero disables a limit
+type RetentionPolicy struct {
+	TTL         time.Duration // Finished sessions older than this are evicted
+	MaxSessions int           // Oldest finished sessions go first once there are more than this
+	MaxBytes    int64         // Same, for the estimated size of all sessions together
+	ArchiveDir  string        // Evicted sessions are written here as JSON first; "" just drops them
+}
+
+// How often the janitor enforces the retention policy
+const janitorInterval = time.Minute
+
+// retentionFromEnv reads SESSION_TTL, MAX_SESSIONS, MAX_SESSION_MEMORY_MB and SESSION_ARCHIVE_DIR
+func retentionFromEnv() (RetentionPolicy, error) {
+	policy := RetentionPolicy{
+		TTL:         7 * 24 * time.Hour,
+		MaxSessions: 1000,
+		MaxBytes:    256 << 20,
+		ArchiveDir:  os.Getenv("SESSION_ARCHIVE_DIR"),
+	}
+	if v := os.Getenv("SESSION_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return policy, fmt.Errorf("SESSION_TTL: %w", err)
+		}
+		policy.TTL = ttl
+	}
+	if v := os.Getenv("MAX_SESSIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return policy, fmt.Errorf("MAX_SESSIONS: %w", err)
+		}
+		policy.MaxSessions = n
+	}
+	if v := os.Getenv("MAX_SESSION_MEMORY_MB"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return policy, fmt.Errorf("MAX_SESSION_MEMORY_MB: %w", err)
+		}
+		policy.MaxBytes = n << 20
+	}
+	return policy, nil
+}
+
+// retention is the policy in force; set once at startup
+var retention RetentionPolicy
+
+// startJanitor enforces the retention policy in the background until the process exits
+func startJanitor(policy RetentionPolicy) {
+	retention = policy
+	if policy.ArchiveDir != "" {
+		if err := os.MkdirAll(policy.ArchiveDir, 0o700); err != nil {
+			log.Printf("[ERROR] creating archive dir: %v", err)
+		}
+	}
+	go func() {
+		for range time.Tick(janitorInterval) {
+			enforceRetention(policy, time.Now())
+		}
+	}()
+}
+
+// enforceRetention evicts (and optionally archives) the sessions the policy no longer allows
+func enforceRetention(policy RetentionPolicy, now time.Time) {
+	for _, s := range retentionVictims(policy, now) {
+		if policy.ArchiveDir != "" {
+			if err := archiveSession(policy.ArchiveDir, s); err != nil {
+				// Keep it in memory rather than lose it; the next run tries again
+				log.Printf("[ERROR] archiving session %s: %v", s.ID, err)
+				continue
+			}
+		}
+		if removeSession(s.ID) {
+			log.Printf("[INFO] Evicted session %s", s.ID)
+		}
+	}
+}
+
+// retentionVictims picks the finished sessions to evict, oldest first.
+// Running sessions are never evicted; they are reconsidered once they finish.
+func retentionVictims(policy RetentionPolicy, now time.Time) []*RedditSession {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	var finished []*RedditSession
+	var total int64
+	sizes := make(map[*RedditSession]int64, len(sessions))
+	for _, s := range sessions {
+		s.mu.Lock()
+		sizes[s] = sessionSize(s)
+		if s.finished() {
+			finished = append(finished, s)
+		}
+		s.mu.Unlock()
+		total += sizes[s]
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+
+	count := len(sessions)
+	var victims []*RedditSession
+	for _, s := range finished {
+		expired := policy.TTL > 0 && now.Sub(s.CreatedAt) > policy.TTL
+		tooMany := policy.MaxSessions > 0 && count > policy.MaxSessions
+		tooBig := policy.MaxBytes > 0 && total > policy.MaxBytes
+		if !expired && !tooMany && !tooBig {
+			// Everything after this one is newer, so only the caps could still apply, and they don't
+			break
+		}
+		victims = append(victims, s)
+		count--
+		total -= sizes[s]
+	}
+	return victims
+}
+
+// sessionSize estimates the memory held by a session's text; callers hold s.mu
+func sessionSize(s *RedditSession) int64 {
+	n := int64(len(s.ID) + len(s.Prompt) + len(s.Subreddit) + len(s.Model) + len(s.Owner))
+	for _, st := range s.SelectedStances {
+		n += int64(len(st.Type) + len(st.SubType) + len(st.Summary))
+	}
+	n += commentsSize(s.Responses)
+	for _, e := range s.Events {
+		n += int64(len(e.HTML))
+	}
+	if s.Summary != nil {
+		for _, list := range [][]string{s.Summary.Themes, s.Summary.Consensus, s.Summary.Conflicts, s.Summary.BlindSpots, s.Summary.ReflectionQuestions} {
+			for _, item := range list {
+				n += int64(len(item))
+			}
+		}
+	}
+	return n
+}
+
+func commentsSize(comments []SimulatedComment) int64 {
+	var n int64
+	for _, c := range comments {
+		n += int64(len(c.Username)+len(c.Flair)+len(c.Text)) + commentsSize(c.Replies)
+	}
+	return n
+}
+
+// archivePath is where an evicted session is kept
+func archivePath(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+func archiveSession(dir string, s *RedditSession) error {
+	data, err := marshalSimulation(s, true)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(archivePath(dir, s.ID), data, 0o600)
+}
+
+// validSessionID reports whether id has the shape randomID gives, so it is safe in a file name
+func validSessionID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// archivedOwner reads who owns an archived session; ok is false without an archive copy
+func archivedOwner(id string) (owner string, ok bool) {
+	if retention.ArchiveDir == "" || !validSessionID(id) {
+		return "", false
+	}
+	data, err := os.ReadFile(archivePath(retention.ArchiveDir, id))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[ERROR] reading archived session %s: %v", id, err)
+		}
+		return "", false
+	}
+	var doc struct {
+		Owner string `json:"owner"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Printf("[ERROR] reading archived session %s: %v", id, err)
+		return "", false
+	}
+	return doc.Owner, true
+}
+
+// canDelete reports whether user may delete a session, whether it is still in
+// memory or was already evicted to the archive. The rules are those of canView.
+func canDelete(id, user string) bool {
+	if s, ok := GetSession(id); ok {
+		return s.canView(user)
+	}
+	owner, ok := archivedOwner(id)
+	return ok && (owner == "" || owner == user)
+}
+
+// removeSession drops a session and everything pointing at it from memory.
+// A session still generating is cancelled; the pipeline stops at its next check.
+// Live viewers get a final deleted event so they stop reconnecting.
+func removeSession(id string) bool {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	s, ok := sessions[id]
+	if !ok {
+		return false
+	}
+	delete(sessions, id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.Shares {
+		delete(shareIndex, l.ID)
+	}
+	if !s.finished() {
+		s.setState(StateCancelled)
+	}
+	s.appendEvent(SessionEvent{Type: EventDeleted})
+	s.deleted = true
+	s.notify() // Live viewers get the deleted event right away
+	return true
+}
+
+// DeleteSession removes a session for good: from memory, its share links and any archive copy
+func DeleteSession(id string) bool {
+	ok := removeSession(id)
+	if retention.ArchiveDir != "" && validSessionID(id) {
+		err := os.Remove(archivePath(retention.ArchiveDir, id))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[ERROR] deleting archived session %s: %v", id, err)
+		}
+	}
+	return ok
+}
+
+// DeleteButton lets the owner delete a session immediately
+func DeleteButton(sessionID string) *Node {
+	return Form(Method("POST"), Action("/delete"),
+		Attr("onsubmit", "return confirm('Delete this session for good? This cannot be undone.')"),
+		Input(Type("hidden"), Name("id"), Value(sessionID)),
+		Button(Type("submit"), Class("btn btn-sm btn-error btn-outline"), T("Delete now")),
+	)
+}
+
+// deleteHandler serves POST /delete
+func deleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+	// Archived sessions are no longer in memory, so authorizeSession can't find them
+	id := r.FormValue("id")
+	if id == "" {
+		http.Error(w, "Missing session ID", http.StatusBadRequest)
+		return
+	}
+	if !canDelete(id, CurrentUser(r)) {
+		http.Error(w, "Invalid session ID", http.StatusNotFound)
+		return
+	}
+	DeleteSession(id)
+	log.Printf("[INFO] Deleted session %s", id)
+	http.Redirect(w, r, "/history", http.StatusSeeOther)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---------- SHARE LINKS ----------
+
+// A read-only link to one session. The token handed out is signed, so it can't be
+// altered to point at another session, and it only works while the ShareLink
+// it names still exists unrevoked on the session. Tokens carry only the share ID,
+// never the session ID.
+type ShareLink struct {
+	ID        string
+	CreatedAt time.Time
+	ExpiresAt time.Time // Zero means the link never expires
+	Revoked   bool
+}
+
+// Lifetimes offered when creating a share link; "" never expires
+var shareExpiryOptions = []struct {
+	Value string
+	Label string
+	TTL   time.Duration
+}{
+	{Value: "1h", Label: "1 hour", TTL: time.Hour},
+	{Value: "24h", Label: "1 day", TTL: 24 * time.Hour},
+	{Value: "168h", Label: "1 week", TTL: 7 * 24 * time.Hour},
+	{Value: "", Label: "Never"},
+}
+
+// Share ID -> session ID, guarded by sessionsMutex
+var shareIndex = make(map[string]string)
+
+// shareSecret signs share tokens. Set SHARE_SECRET to keep links valid across restarts.
+var shareSecret = func() []byte {
+	if s := os.Getenv("SHARE_SECRET"); s != "" {
+		return []byte(s)
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}()
+
+func (l ShareLink) expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && now.After(l.ExpiresAt)
+}
+
+// shareToken encodes "<share>.<expiry>" and its HMAC
+func shareToken(l ShareLink) string {
+	var expiry int64
+	if !l.ExpiresAt.IsZero() {
+		expiry = l.ExpiresAt.Unix()
+	}
+	payload := fmt.Sprintf("%s.%d", l.ID, expiry)
+	mac := hmac.New(sha256.New, shareSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// resolveShareToken verifies a token and returns the session it grants access to
+func resolveShareToken(token string) (*RedditSession, bool) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, shareSecret)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, false
+	}
+
+	shareID, encExpiry, ok := strings.Cut(string(payload), ".")
+	if !ok {
+		return nil, false
+	}
+	expiry, err := strconv.ParseInt(encExpiry, 10, 64)
+	if err != nil || (expiry != 0 && time.Now().Unix() > expiry) {
+		return nil, false
+	}
+
+	sessionsMutex.Lock()
+	session, ok := sessions[shareIndex[shareID]]
+	sessionsMutex.Unlock()
+	if !ok {
+		return nil, false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, l := range session.Shares {
+		if l.ID == shareID {
+			return session, !l.Revoked && !l.expired(time.Now())
+		}
+	}
+	return nil, false
+}
+
+// CreateShareLink adds a new share link to the session
+func CreateShareLink(s *RedditSession, ttl time.Duration) ShareLink {
+	l := ShareLink{ID: randomToken()[:12], CreatedAt: time.Now()}
+	if ttl > 0 {
+		l.ExpiresAt = l.CreatedAt.Add(ttl)
+	}
+	s.mu.Lock()
+	s.Shares = append(s.Shares, l)
+	s.mu.Unlock()
+	sessionsMutex.Lock()
+	shareIndex[l.ID] = s.ID
+	sessionsMutex.Unlock()
+	return l
+}
+
+// RevokeShareLink disables a share link, reporting whether it existed
+func RevokeShareLink(s *RedditSession, shareID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.Shares {
+		if s.Shares[i].ID == shareID {
+			s.Shares[i].Revoked = true
+			return true
+		}
+	}
+	return false
+}
+
+// SharePanel lets the owner create, copy and revoke share links
+func SharePanel(sessionID string, links []ShareLink) *Node {
+	expiry := Select(Name("expires"), Class("border rounded p-1 text-sm"))
+	for _, o := range shareExpiryOptions {
+		expiry.Children = append(expiry.Children, Option(Value(o.Value), Text(o.Label)))
+	}
+
+	list := Ul(Class("space-y-2 mt-3"))
+	now := time.Now()
+	for _, l := range links {
+		if l.Revoked || l.expired(now) {
+			continue
+		}
+		href := "/shared?token=" + shareToken(l)
+		expires := "never expires"
+		if !l.ExpiresAt.IsZero() {
+			expires = "expires " + l.ExpiresAt.Format("2006-01-02 15:04")
+		}
+		list.Children = append(list.Children, Li(Class("flex flex-wrap items-center gap-2 text-sm"),
+			A(Href(href), Class("text-blue-600 hover:underline"), Target("_blank"), T("Read-only link")),
+			Span(Class("text-gray-500"), Text(expires)),
+			Button(Type("button"), Class("btn btn-xs"), Attr("data-href", href),
+				OnClick("navigator.clipboard.writeText(new URL(this.dataset.href, location.href).href)"), T("Copy")),
+			Form(Method("POST"), Action("/share/revoke"), Class("inline"),
+				Input(Type("hidden"), Name("id"), Value(sessionID)),
+				Input(Type("hidden"), Name("share"), Value(l.ID)),
+				Button(Type("submit"), Class("btn btn-xs btn-error btn-outline"), T("Revoke")),
+			),
+		))
+	}
+
+	return Details(Class("bg-white p-4 rounded shadow"),
+		Summary(Class("cursor-pointer font-semibold"), T("Share")),
+		P(Class("text-sm text-gray-500 mt-2"),
+			T("Share links show the post and thread only. Viewers can't see your history, fork or reply."),
+		),
+		Form(Method("POST"), Action("/share"), Class("flex items-center gap-2 mt-3"),
+			Input(Type("hidden"), Name("id"), Value(sessionID)),
+			Label(Class("text-sm"), T("Expires after")),
+			expiry,
+			Button(Type("submit"), Class("btn btn-sm"), T("Create link")),
+		),
+		list,
+	)
+}
+
+// SharedSessionPage is the read-only view behind a share link. It leaves out
+// everything identifying: no session ID, owner, lineage, timestamps or actions.
+func SharedSessionPage(s *RedditSession) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	title := s.Subreddit
+	if sub, ok := LookupSubreddit(s.Subreddit); ok {
+		title = sub.Label
+	}
+	return DefaultLayout(
+		Div(Class("max-w-2xl mx-auto p-6 space-y-6"),
+			H1(Class("text-2xl font-bold"), Text(fmt.Sprintf("%s simulation", title))),
+			If(!s.finished(), P(Class("text-sm text-gray-500 italic"), T("This thread is still being generated. Refresh to see more.")), Nil()),
+			Ch(staticThread(s)),
+			P(Class("text-xs text-gray-500"), T("Shared from ShadowReddit. ShadowReddit is not affiliated with Reddit in anyway.")),
+		),
+	)
+}
+
+func registerShareRoutes() {
+	http.HandleFunc("/share", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		session, ok := authorizeSession(w, r, r.FormValue("id"))
+		if !ok {
+			return
+		}
+
+		var ttl time.Duration
+		valid := false
+		for _, o := range shareExpiryOptions {
+			if o.Value == r.FormValue("expires") {
+				ttl, valid = o.TTL, true
+			}
+		}
+		if !valid {
+			http.Error(w, "Unknown expiry", http.StatusBadRequest)
+			return
+		}
+
+		CreateShareLink(session, ttl)
+		log.Printf("[INFO] Created share link for session %s", session.ID)
+		http.Redirect(w, r, "/session?id="+session.ID, http.StatusSeeOther)
+	})
+
+	http.HandleFunc("/share/revoke", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+		session, ok := authorizeSession(w, r, r.FormValue("id"))
+		if !ok {
+			return
+		}
+		if !RevokeShareLink(session, r.FormValue("share")) {
+			http.Error(w, "Unknown share link", http.StatusNotFound)
+			return
+		}
+		http.Redirect(w, r, "/session?id="+session.ID, http.StatusSeeOther)
+	})
+
+	http.HandleFunc("/shared", func(w http.ResponseWriter, r *http.Request) {
+		session, ok := resolveShareToken(r.URL.Query().Get("token"))
+		if !ok {
+			http.Error(w, "This share link is invalid, expired or has been revoked", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		ServeNode(SharedSessionPage(session))(w, r)
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// payloadOf decodes the signed part of a share token
+func payloadOf(token string) string {
+	enc, _, _ := strings.Cut(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(enc)
+	return string(payload)
+}
+
+func TestResolveShareToken(t *testing.T) {
+	// forge swaps in a new payload but keeps the original signature
+	forge := func(token, payload string) string {
+		_, sig, _ := strings.Cut(token, ".")
+		return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + sig
+	}
+
+	tests := []struct {
+		name  string
+		token func(s *RedditSession) string
+		ok    bool
+	}{
+		{"valid", func(s *RedditSession) string {
+			return shareToken(CreateShareLink(s, time.Hour))
+		}, true},
+		{"never expires", func(s *RedditSession) string {
+			return shareToken(CreateShareLink(s, 0))
+		}, true},
+		{"tampered signature", func(s *RedditSession) string {
+			// Change a whole byte of the MAC, not just base64 padding bits
+			token := shareToken(CreateShareLink(s, time.Hour))
+			payload, enc, _ := strings.Cut(token, ".")
+			sig, _ := base64.RawURLEncoding.DecodeString(enc)
+			sig[0] ^= 0xff
+			return payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+		}, false},
+		{"tampered expiry", func(s *RedditSession) string {
+			l := CreateShareLink(s, time.Hour)
+			return forge(shareToken(l), l.ID+".0")
+		}, false},
+		{"payload of another link", func(s *RedditSession) string {
+			// Both links are valid, so only the signature can tell them apart
+			a, b := CreateShareLink(s, time.Hour), CreateShareLink(s, time.Hour)
+			return forge(shareToken(a), strings.Replace(payloadOf(shareToken(a)), a.ID, b.ID, 1))
+		}, false},
+		{"malformed", func(s *RedditSession) string {
+			CreateShareLink(s, 0)
+			return "not-a-token"
+		}, false},
+		{"bad base64", func(s *RedditSession) string {
+			return "!!!.???"
+		}, false},
+		{"expired", func(s *RedditSession) string {
+			l := CreateShareLink(s, time.Hour)
+			s.mu.Lock()
+			s.Shares[len(s.Shares)-1].ExpiresAt = time.Now().Add(-time.Minute)
+			s.mu.Unlock()
+			l.ExpiresAt = time.Now().Add(-time.Minute)
+			return shareToken(l)
+		}, false},
+		{"revoked", func(s *RedditSession) string {
+			l := CreateShareLink(s, time.Hour)
+			RevokeShareLink(s, l.ID)
+			return shareToken(l)
+		}, false},
+		{"deleted session", func(s *RedditSession) string {
+			token := shareToken(CreateShareLink(s, time.Hour))
+			DeleteSession(s.ID)
+			return token
+		}, false},
+		{"unknown share", func(s *RedditSession) string {
+			return shareToken(ShareLink{ID: "nosuchshare0"})
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession("A very personal post", "relationships", "alice")
+			t.Cleanup(func() { DeleteSession(s.ID) })
+
+			got, ok := resolveShareToken(tt.token(s))
+			if ok != tt.ok {
+				t.Fatalf("resolveShareToken ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != s {
+				t.Errorf("token resolved to session %s, want %s", got.ID, s.ID)
+			}
+		})
+	}
+}
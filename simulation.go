@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- SIMULATION PIPELINE ----------
+
+// Each top-level comment gets between 1 and this many replies
+const maxRepliesPerComment = 2
+
+// runSimulation generates the whole thread for sess and returns once it is finished.
+// Anything the session already has (stances, comments, replies, summary) is kept,
+// so it also continues imported or partially generated threads.
+// Callers serving HTTP should run it in its own goroutine.
+func runSimulation(client *openai.Client, sess *RedditSession) {
+	var wg sync.WaitGroup
+
+	sess.mu.Lock()
+	prompt, subreddit, model := sess.Prompt, sess.Subreddit, sess.Model
+	selectedStances := sess.SelectedStances
+	if sess.rng == nil {
+		sess.rng = rand.New(rand.NewSource(sess.Seed))
+	}
+	rng := sess.rng
+	if len(selectedStances) == 0 {
+		sess.advance(StateSelectingStances)
+	}
+	sess.mu.Unlock()
+
+	// Every random choice comes from rng on this goroutine, in a fixed order,
+	// so the same Seed always makes the same decisions.
+	nextSeed := func() *int {
+		n := int(rng.Int31())
+		return &n
+	}
+
+	// 1) Get stances from GPT, unless the caller already chose them
+	if len(selectedStances) == 0 {
+		var err error
+		selectedStances, err = generateStances(client, model, nextSeed(), subreddit, prompt)
+		if err != nil {
+			log.Printf("[ERROR] generating stances: %v", err)
+			sess.mu.Lock()
+			sess.recordFailure(StageStances, "", err, true)
+			sess.advance(StateFailed)
+			sess.mu.Unlock()
+			return
+		}
+		rng.Shuffle(len(selectedStances), func(i, j int) {
+			selectedStances[i], selectedStances[j] = selectedStances[j], selectedStances[i]
+		})
+
+		// 2) Store stances in the session
+		sess.mu.Lock()
+		sess.SelectedStances = selectedStances
+		sess.publish()
+		sess.mu.Unlock()
+	}
+
+	sess.mu.Lock()
+	sess.advance(StateGenerating)
+	sess.mu.Unlock()
+
+	// Spawn a goroutine that generates 1 to maxRepliesPerComment replies to a
+	// top-level comment, one after another so they always land in the same order
+	spawnReplies := func(parentIndex int, parentText string) {
+		count := 1 + rng.Intn(maxRepliesPerComment)
+		usernames := make([]string, count)
+		seeds := make([]*int, count)
+		for i := range count {
+			usernames[i], seeds[i] = randomReplyUsername(rng), nextSeed()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range count {
+				if sess.isCancelled() {
+					return
+				}
+				replyText, err := GenerateReplyToComment(client, model, seeds[i], prompt, parentText)
+				if err != nil {
+					log.Printf("[ERROR] generating reply: %v", err)
+					// A missing reply is worth a notice, not the whole session
+					sess.mu.Lock()
+					sess.recordFailure(StageReply, commentID(parentIndex), err, false)
+					sess.publish()
+					sess.mu.Unlock()
+					continue
+				}
+
+				child := SimulatedComment{
+					Username: usernames[i],
+					Flair:    "reply",
+					Text:     replyText,
+				}
+
+				sess.mu.Lock()
+				sess.Responses[parentIndex].Replies = append(sess.Responses[parentIndex].Replies, child)
+				sess.publish()
+				sess.mu.Unlock()
+			}
+		}()
+	}
+
+	// 3) Imported or forked threads may already have comments; they only need replies
+	sess.mu.Lock()
+	existing := len(sess.Responses)
+	for i, c := range sess.Responses {
+		if len(c.Replies) == 0 {
+			spawnReplies(i, c.Text)
+		}
+	}
+	sess.mu.Unlock()
+
+	// 4) For each remaining stance, generate a single top-level comment
+	for _, stance := range selectedStances[min(existing, len(selectedStances)):] {
+		// A deleted post must not be sent to the model again
+		if sess.isCancelled() {
+			break
+		}
+
+		// Build the top-level comment
+		comment := SimulatedComment{
+			Username: fmt.Sprintf("%s_%s", stance.Type, stance.SubType),
+			Flair:    stance.Type,
+			Stance:   &stance,
+		}
+
+		// In aita mode every top-level comment carries a structured verdict
+		if subreddit == "aita" {
+			resp, err := GenerateVerdictFromStance(client, model, nextSeed(), prompt, stance)
+			if err != nil {
+				log.Printf("[ERROR] generating verdict response: %v", err)
+				sess.mu.Lock()
+				sess.recordFailure(StageComment, "", err, true)
+				sess.mu.Unlock()
+				break
+			}
+			comment.Text = resp.Text
+			comment.Verdict = resp.Verdict
+			comment.Score = resp.Upvotes
+		} else {
+			text, err := GenerateResponseFromStance(client, model, nextSeed(), prompt, stance)
+			if err != nil {
+				log.Printf("[ERROR] generating response: %v", err)
+				sess.mu.Lock()
+				sess.recordFailure(StageComment, "", err, true)
+				sess.mu.Unlock()
+				break
+			}
+			comment.Text = text
+		}
+
+		// Append to session and get its index
+		sess.mu.Lock()
+		idx := len(sess.Responses)
+		sess.Responses = append(sess.Responses, comment)
+		sess.publish()
+		sess.mu.Unlock()
+
+		spawnReplies(idx, comment.Text)
+	}
+
+	// 5) Once ALL replies are done, summarize the thread and mark the session done
+	wg.Wait()
+
+	sess.mu.Lock()
+	thread := append([]SimulatedComment(nil), sess.Responses...)
+	summarized := sess.Summary != nil || sess.State == StateCancelled
+	if len(thread) > 0 && !summarized {
+		sess.advance(StateSummarizing)
+	}
+	sess.mu.Unlock()
+
+	if len(thread) > 0 && !summarized {
+		summary, err := GenerateThreadSummary(client, model, nextSeed(), prompt, thread)
+		if err != nil {
+			// A missing summary shouldn't hide the thread itself
+			log.Printf("[ERROR] summarizing thread: %v", err)
+			sess.mu.Lock()
+			sess.recordFailure(StageSummary, "", err, false)
+			sess.publish()
+			sess.mu.Unlock()
+		} else {
+			sess.mu.Lock()
+			sess.Summary = summary
+			sess.publish()
+			sess.mu.Unlock()
+		}
+	}
+
+	sess.mu.Lock()
+	if _, failed := sess.fatalFailure(); failed {
+		sess.advance(StateFailed)
+	} else {
+		sess.advance(StateDone)
+	}
+	sess.mu.Unlock()
+}
+
+// advance moves the session to next unless it was cancelled meanwhile; callers hold s.mu
+func (s *RedditSession) advance(next SessionState) {
+	if s.State != StateCancelled {
+		s.setState(next)
+	}
+}
+
+// isCancelled reports whether the session was deleted while it was generating
+func (s *RedditSession) isCancelled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State == StateCancelled
+}
+
+// modelOr returns model, or fallback if the session didn't ask for a specific one
+func modelOr(model, fallback string) string {
+	if model == "" {
+		return fallback
+	}
+	return model
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeOpenAI answers chat completions the way runSimulation expects, without the network
+type fakeOpenAI struct {
+	mu    sync.Mutex
+	calls map[string]int // By kind: stances, verdict, comment, reply, summary
+
+	// failComment, if set, makes the n-th comment request (1-based) fail
+	failComment int
+	// gate, if set, holds every comment request after the first until it is closed
+	gate chan struct{}
+}
+
+func newFakeOpenAI(t *testing.T) (*fakeOpenAI, *openai.Client) {
+	f := &fakeOpenAI{calls: map[string]int{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = srv.URL + "/v1"
+	return f, openai.NewClientWithConfig(config)
+}
+
+func (f *fakeOpenAI) count(kind string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[kind]
+}
+
+func (f *fakeOpenAI) serve(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	kind := "comment"
+	if name, ok := req.FunctionCall.(map[string]any); ok {
+		switch name["name"] {
+		case "select_stances":
+			kind = "stances"
+		case "post_verdict_comment":
+			kind = "verdict"
+		case "summarize_thread":
+			kind = "summary"
+		}
+	} else if len(req.Messages) > 0 && strings.HasPrefix(req.Messages[0].Content, "You are simulating a reply") {
+		kind = "reply"
+	}
+
+	f.mu.Lock()
+	f.calls[kind]++
+	n, failComment, gate := f.calls[kind], f.failComment, f.gate
+	f.mu.Unlock()
+
+	var args any
+	text := fmt.Sprintf("%s %d", kind, n)
+	switch kind {
+	case "stances":
+		args = StanceSelectionResponse{Stances: AllStances[:5]}
+	case "verdict":
+		args = VerdictCommentResponse{Verdict: VerdictNTA, Text: text, Upvotes: 10 * n}
+	case "summary":
+		args = ThreadSummary{Themes: []string{text}}
+	}
+	if kind == "comment" || kind == "verdict" {
+		if n == failComment {
+			http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+			return
+		}
+		if gate != nil && n > 1 {
+			<-gate
+		}
+	}
+
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: text}
+	if args != nil {
+		data, _ := json.Marshal(args)
+		msg = openai.ChatCompletionMessage{
+			Role:         openai.ChatMessageRoleAssistant,
+			FunctionCall: &openai.FunctionCall{Name: kind, Arguments: string(data)},
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+		Object:  "chat.completion",
+		Choices: []openai.ChatCompletionChoice{{Message: msg, FinishReason: openai.FinishReasonStop}},
+	})
+}
+
+// states lists the states the session went through; callers hold s.mu
+func states(s *RedditSession) []SessionState {
+	var list []SessionState
+	for _, t := range s.Transitions {
+		list = append(list, t.State)
+	}
+	return list
+}
+
+// waitFinished waits for a session generating in the background to stop
+func waitFinished(t *testing.T, s *RedditSession) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s.mu.Lock()
+		finished := s.finished()
+		s.mu.Unlock()
+		if finished {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session never finished")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunSimulationTransitions(t *testing.T) {
+	for _, subreddit := range []string{"aita", "askreddit"} {
+		t.Run(subreddit, func(t *testing.T) {
+			_, client := newFakeOpenAI(t)
+			s := NewSession("My roommate ate my leftovers", subreddit, "")
+			t.Cleanup(func() { DeleteSession(s.ID) })
+
+			runSimulation(client, s)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			want := []SessionState{StatePending, StateSelectingStances, StateGenerating, StateSummarizing, StateDone}
+			if got := states(s); !slices.Equal(got, want) {
+				t.Errorf("transitions = %v, want %v", got, want)
+			}
+			if len(s.Responses) != 5 {
+				t.Fatalf("got %d comments, want 5", len(s.Responses))
+			}
+			for i, c := range s.Responses {
+				if len(c.Replies) == 0 || len(c.Replies) > maxRepliesPerComment {
+					t.Errorf("comment %d has %d replies", i, len(c.Replies))
+				}
+				if (subreddit == "aita") != (c.Verdict != "") {
+					t.Errorf("comment %d has verdict %q", i, c.Verdict)
+				}
+			}
+			if s.Summary == nil {
+				t.Error("thread was not summarized")
+			}
+			if last := s.Events[len(s.Events)-1]; last.Type != EventDone || last.Status != string(StateDone) {
+				t.Errorf("last event = %s %q, want done", last.Type, last.Status)
+			}
+		})
+	}
+}
+
+// TestRunSimulationConcurrent deletes a session mid-generation while viewers
+// stream it, OP replies and the janitor runs; go test -race checks the locking.
+func TestRunSimulationConcurrent(t *testing.T) {
+	fake, client := newFakeOpenAI(t)
+	fake.gate = make(chan struct{})
+	s := NewSession("Should I tell my friend the truth?", "relationships", "")
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	firstComment := make(chan struct{})
+	var once sync.Once
+
+	// Viewers follow the stream until the deleted event ends it
+	streams := make([][]SessionEvent, 2)
+	for i := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := streamEvents(context.Background(), s, 0, func(e SessionEvent) error {
+				streams[i] = append(streams[i], e)
+				if e.Type == EventComment {
+					once.Do(func() { close(firstComment) })
+				}
+				return nil
+			}, nil)
+			if err != nil {
+				t.Errorf("viewer %d: %v", i, err)
+			}
+		}()
+	}
+
+	// OP replies to the first comment as soon as it exists
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for AddOPReply(s, "c0", "Thanks, everyone") != nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+
+	// The janitor only ever evicts finished sessions
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			enforceRetention(RetentionPolicy{TTL: time.Nanosecond}, time.Now().Add(time.Hour))
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		runSimulation(client, s)
+		close(done)
+	}()
+
+	select {
+	case <-firstComment:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no comment was streamed")
+	}
+	if !DeleteSession(s.ID) {
+		t.Fatal("session was gone before it was deleted")
+	}
+	close(fake.gate)
+	<-done
+	close(stop)
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	want := []SessionState{StatePending, StateSelectingStances, StateGenerating, StateCancelled}
+	if got := states(s); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+	if n := fake.count("comment"); n > 2 {
+		t.Errorf("%d comments were requested after the session was deleted", n-1)
+	}
+	if fake.count("summary") != 0 {
+		t.Error("a deleted session was summarized")
+	}
+
+	for i, events := range streams {
+		seq := 0
+		for _, e := range events {
+			if e.Type == EventPresence {
+				continue
+			}
+			if e.Seq != seq+1 {
+				t.Errorf("viewer %d: seq %d after %d", i, e.Seq, seq)
+			}
+			seq = e.Seq
+		}
+		if last := events[len(events)-1]; last.Type != EventDeleted {
+			t.Errorf("viewer %d: last event = %s, want deleted", i, last.Type)
+		}
+	}
+}
+
+func TestRetryResummarizes(t *testing.T) {
+	fake, client := newFakeOpenAI(t)
+	fake.failComment = 3
+	s := NewSession("I skipped my sister's wedding", "askreddit", "")
+	t.Cleanup(func() { DeleteSession(s.ID) })
+
+	runSimulation(client, s)
+
+	s.mu.Lock()
+	if s.State != StateFailed || len(s.Responses) != 2 || s.Summary == nil {
+		t.Fatalf("after a failed comment: state %s, %d comments, summary %v", s.State, len(s.Responses), s.Summary)
+	}
+	// The chart counts the two posted comments, not the five planned stances
+	var chart []Stance
+	for _, e := range s.Events {
+		if e.Type == EventDistribution {
+			chart = e.Stances
+		}
+	}
+	if want := commentStances(s.Responses); len(chart) != 2 || !slices.Equal(chart, want) {
+		t.Errorf("distribution = %v, want %v", chart, want)
+	}
+	s.mu.Unlock()
+
+	if !RetrySession(client, s) {
+		t.Fatal("nothing to retry")
+	}
+	waitFinished(t, s)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	want := []SessionState{
+		StatePending, StateSelectingStances, StateGenerating, StateSummarizing, StateFailed,
+		StatePending, StateGenerating, StateSummarizing, StateDone,
+	}
+	if got := states(s); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+	if len(s.Responses) != 5 {
+		t.Errorf("got %d comments after the retry, want 5", len(s.Responses))
+	}
+	if n := fake.count("summary"); n != 2 {
+		t.Fatalf("thread was summarized %d times, want 2", n)
+	}
+	if got := s.Summary.Themes; !slices.Equal(got, []string{"summary 2"}) {
+		t.Errorf("summary themes = %v, want the retry's summary", got)
+	}
+}
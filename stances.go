@@ -40,3 +40,26 @@ var AllStances = []Stance{
 	{Type: "meta", SubType: "call_out_subreddit", Summary: "Comments on how typical or cliché the post is."},
 	{Type: "meta", SubType: "structure_commentary", Summary: "Critiques how the post is written or what it omits."},
 }
+
+// LookupStance finds a predefined stance by Type and SubType
+func LookupStance(stanceType, subType string) (Stance, bool) {
+	for _, s := range AllStances {
+		if s.Type == stanceType && s.SubType == subType {
+			return s, true
+		}
+	}
+	return Stance{}, false
+}
+
+// StanceTypes lists the distinct stance Types in the order they appear in AllStances
+func StanceTypes() []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, s := range AllStances {
+		if !seen[s.Type] {
+			seen[s.Type] = true
+			types = append(types, s.Type)
+		}
+	}
+	return types
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"time"
+)
+
+// ---------- SESSION LIFECYCLE ----------
+
+// SessionState is where a session is in its lifecycle
+type SessionState string
+
+const (
+	StatePending          SessionState = "pending"
+	StateSelectingStances SessionState = "selecting_stances"
+	StateGenerating       SessionState = "generating"
+	StateSummarizing      SessionState = "summarizing"
+	StateDone             SessionState = "done"
+	StateFailed           SessionState = "failed"    // A fatal failure stopped generation
+	StateCancelled        SessionState = "cancelled" // Deleted while generating
+)
+
+// The transitions runSimulation, RetrySession, ImportSession and deletion may make.
+// Done and Failed go back to Pending only for a retry; Cancelled is final.
+var stateTransitions = map[SessionState][]SessionState{
+	StatePending:          {StateSelectingStances, StateGenerating, StateDone, StateCancelled},
+	StateSelectingStances: {StateGenerating, StateFailed, StateCancelled},
+	StateGenerating:       {StateSummarizing, StateDone, StateFailed, StateCancelled},
+	StateSummarizing:      {StateDone, StateFailed, StateCancelled},
+	StateDone:             {StatePending},
+	StateFailed:           {StatePending},
+	StateCancelled:        {},
+}
+
+// StateTransition records when a session entered a state
+type StateTransition struct {
+	State SessionState `json:"state"`
+	At    time.Time    `json:"at"`
+}
+
+// finished reports whether nothing is generating for the session any more
+func (st SessionState) finished() bool {
+	return st == StateDone || st == StateFailed || st == StateCancelled
+}
+
+// setState moves the session to next and records when. Transitions the lifecycle
+// doesn't allow are refused and logged, so a bug can't resurrect a cancelled
+// session or skip a step unnoticed. Callers hold s.mu.
+func (s *RedditSession) setState(next SessionState) bool {
+	if s.State == next {
+		return true
+	}
+	if !slices.Contains(stateTransitions[s.State], next) {
+		log.Printf("[ERROR] session %s: refused state change %s -> %s", s.ID, s.State, next)
+		return false
+	}
+	s.State = next
+	s.Transitions = append(s.Transitions, StateTransition{State: next, At: time.Now()})
+	s.publish()
+	return true
+}
+
+// finished reports whether the session has stopped generating; callers hold s.mu
+func (s *RedditSession) finished() bool {
+	return s.State.finished()
+}
+
+// status is the coarse state shown in listings and the API: running, done, failed or cancelled; callers hold s.mu
+func (s *RedditSession) status() string {
+	if s.finished() {
+		return string(s.State)
+	}
+	return "running"
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+)
+
+// ---------- STATIC ASSETS ----------
+
+// Page scripts, served under /static/ straight from the binary
+//
+//go:embed static
+var staticFiles embed.FS
+
+// Content hash of each static file, so links change whenever the file does
+var staticVersions = func() map[string]string {
+	versions := map[string]string{}
+	fs.WalkDir(staticFiles, "static", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := staticFiles.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		versions[path[len("static/"):]] = hex.EncodeToString(sum[:8])
+		return nil
+	})
+	return versions
+}()
+
+// staticURL links to a file in static/, versioned by its content
+func staticURL(name string) string {
+	return "/static/" + name + "?v=" + staticVersions[name]
+}
+
+// staticHandler serves /static/. Versioned links may be cached for good, since a
+// new version of the file gets a new link; anything else is revalidated.
+func staticHandler() http.Handler {
+	files := http.FileServerFS(staticFiles)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("v") != "" {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,14 @@
+// Comparison page: buttons naming a stance Type in data-filter-stance show only
+// the rows (data-stance) for that Type; an empty value shows everything again.
+function filterStance(type) {
+	document.querySelectorAll("[data-stance]").forEach(function(el) {
+		el.style.display = (!type || el.dataset.stance === type) ? "" : "none";
+	});
+}
+
+document.addEventListener("click", function(event) {
+	let el = event.target.closest("[data-filter-stance]");
+	if (el) {
+		filterStance(el.dataset.filterStance);
+	}
+});
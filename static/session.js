@@ -0,0 +1,209 @@
+// Live view of a session page: follows /ws (or /events) and fills in the thread.
+// The page passes its settings in <script type="application/json" id="sessionConfig">.
+const config = JSON.parse(document.getElementById("sessionConfig").textContent);
+const sessionID = encodeURIComponent(config.id);
+
+let responseArea = document.getElementById("responseArea");
+let stanceFilter = "";
+let lastSeq = 0;         // Seq of the last event shown, for resuming after a drop
+let retryDelay = 1000;
+let wsWorks = false;     // Whether a WebSocket has ever connected from this page
+let gone = false;        // The session was deleted; there is nothing left to follow
+
+// Show only top-level comments (and their replies) written from the given stance Type
+function filterStance(type) {
+	stanceFilter = type;
+	responseArea.querySelectorAll("[data-stance]").forEach(function(el) {
+		el.style.display = (!type || el.dataset.stance === type) ? "" : "none";
+	});
+}
+
+// Stance chart segments and legend entries name their stance in data-filter-stance
+document.addEventListener("click", function(event) {
+	let el = event.target.closest("[data-filter-stance]");
+	if (el) {
+		filterStance(el.dataset.filterStance);
+	}
+});
+
+function handleEvent(data) {
+	if (data.type === "comment") {
+		// Create a container for this top-level comment
+		let parentDiv = document.createElement("div");
+		let stance = data.comment.stance ? data.comment.stance.type : "";
+		parentDiv.setAttribute("id", "comment-" + data.comment.id);
+		parentDiv.dataset.stance = stance;
+		parentDiv.dataset.comment = data.comment.id;
+		if (stanceFilter && stance !== stanceFilter) {
+			parentDiv.style.display = "none";
+		}
+		parentDiv.innerHTML = data.html;
+		// Every top-level comment gets its own Reply as OP form, below its replies
+		let form = document.getElementById("opReplyTemplate").cloneNode(true);
+		form.removeAttribute("id");
+		form.classList.remove("hidden");
+		parentDiv.appendChild(form);
+		responseArea.appendChild(parentDiv);
+
+	} else if (data.type === "reply") {
+		// Append a reply to an existing comment's container
+		let parentDiv = document.getElementById("comment-" + data.comment.parent_id);
+		if (!parentDiv) {
+			console.warn("No parent container found for comment", data.comment.parent_id);
+			return;
+		}
+		let replyDiv = document.createElement("div");
+		replyDiv.innerHTML = data.html;
+		parentDiv.insertBefore(replyDiv, parentDiv.querySelector(".op-reply"));
+
+	} else if (data.type === "distribution") {
+		// Show how the comments posted so far split by stance
+		document.getElementById("stanceArea").innerHTML = data.html;
+
+	} else if (data.type === "summary") {
+		// Show the thread synthesis at the top of the page
+		document.getElementById("summaryArea").innerHTML = data.html;
+
+	} else if (data.type === "judgment") {
+		// Show the thread's overall verdict above the post
+		document.getElementById("judgmentArea").innerHTML = data.html;
+
+	} else if (data.type === "error") {
+		// Failures already rendered with the page aren't shown twice
+		if (document.getElementById("failure-" + data.error.id)) {
+			return;
+		}
+		let notice = document.createElement("div");
+		notice.innerHTML = data.html;
+		// A failed reply is noted under its comment, anything else at the top
+		let parentDiv = data.error.comment_id && document.getElementById("comment-" + data.error.comment_id);
+		(parentDiv || document.getElementById("errorArea")).appendChild(notice);
+
+	} else if (data.type === "retrying") {
+		// Earlier failures no longer apply; generation picks up where it stopped, and
+		// any summary of the partial thread is replaced once the thread is complete
+		document.querySelectorAll(".generation-failure").forEach(function(el) { el.remove(); });
+		document.getElementById("summaryArea").innerHTML = "";
+		let doneNotice = document.getElementById("doneNotice");
+		if (doneNotice) {
+			doneNotice.remove();
+		}
+		document.getElementById("generatingNotice").style.display = "";
+
+	} else if (data.type === "done") {
+		// Signal that simulation is complete
+		document.getElementById("generatingNotice").style.display = "none";
+		let p = document.createElement("p");
+		p.id = "doneNotice";
+		p.innerText = data.status === "done" ? "Simulation complete." : "Simulation stopped.";
+		responseArea.appendChild(p);
+
+	} else if (data.type === "deleted") {
+		sessionGone();
+	}
+}
+
+// Stop following a deleted session and say so
+function sessionGone() {
+	if (gone) {
+		return;
+	}
+	gone = true;
+	document.getElementById("generatingNotice").style.display = "none";
+	let p = document.createElement("p");
+	p.className = "alert alert-warning";
+	p.innerText = "This session was deleted.";
+	responseArea.appendChild(p);
+}
+
+// Resume generation after a failure; the open stream shows its progress
+function retrySession() {
+	fetch("/retry?id=" + sessionID, {method: "POST"});
+}
+
+// Post OP's reply; it comes back over the stream, to this page and every other viewer
+function replyAsOP(form) {
+	let comment = form.closest("[data-comment]").dataset.comment;
+	fetch("/reply?id=" + sessionID + "&comment=" + comment, {method: "POST", body: new URLSearchParams(new FormData(form))}).then(function(resp) {
+		if (resp.ok) {
+			form.reset();
+			form.parentElement.open = false;
+		}
+	});
+	return false;
+}
+
+function showPresence(viewers) {
+	let presence = document.getElementById("presence");
+	presence.innerText = viewers + " people viewing";
+	presence.classList.toggle("hidden", viewers < 2);
+}
+
+// Show each event once, however many times the stream reconnects
+function receive(data) {
+	if (data.type === "presence") {
+		showPresence(data.viewers);
+		return;
+	}
+	if (data.seq <= lastSeq) {
+		return;
+	}
+	lastSeq = data.seq;
+	handleEvent(data);
+}
+
+function connect() {
+	let opened = false;
+	let scheme = window.location.protocol === "https:" ? "wss://" : "ws://";
+	let ws = new WebSocket(scheme + window.location.host + "/ws?id=" + sessionID + "&since=" + lastSeq);
+	ws.onopen = function() {
+		opened = true;
+		wsWorks = true;
+		retryDelay = 1000;
+	};
+	ws.onmessage = function(event) {
+		receive(JSON.parse(event.data));
+	};
+	ws.onclose = function() {
+		if (gone) {
+			return;
+		}
+		if (!opened && !wsWorks) {
+			// The handshake never succeeded (e.g. a proxy blocks WebSockets): use SSE instead
+			connectSSE();
+			return;
+		}
+		if (!opened) {
+			// A refused handshake may mean the session was deleted while we were away;
+			// WebSocket hides the status, so ask the page itself
+			fetch("/session?id=" + sessionID, {method: "HEAD"}).then(function(resp) {
+				if (resp.status === 404) {
+					sessionGone();
+				} else {
+					reconnect();
+				}
+			}, reconnect);
+			return;
+		}
+		reconnect();
+	};
+}
+
+// Reconnect with exponential backoff and replay only what was missed
+function reconnect() {
+	setTimeout(connect, retryDelay);
+	retryDelay = Math.min(retryDelay * 2, 30000);
+}
+
+// EventSource reconnects by itself, resuming with the Last-Event-ID header
+function connectSSE() {
+	let es = new EventSource("/events?id=" + sessionID + "&since=" + lastSeq);
+	es.onmessage = function(event) {
+		receive(JSON.parse(event.data));
+		if (gone) {
+			es.close();
+		}
+	};
+}
+
+connect();
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- THREAD SUMMARY ----------
+
+// ThreadSummary is "what the thread thinks", synthesized once all comments are in
+type ThreadSummary struct {
+	Themes              []string `json:"themes"`
+	Consensus           []string `json:"consensus"`
+	Conflicts           []string `json:"conflicts"`
+	BlindSpots          []string `json:"blind_spots"`
+	ReflectionQuestions []string `json:"reflection_questions"`
+}
+
+// threadTranscript flattens a comment tree into indented plain text for the model
+func threadTranscript(comments []SimulatedComment) string {
+	var b strings.Builder
+	var walk func(cs []SimulatedComment, depth int)
+	walk = func(cs []SimulatedComment, depth int) {
+		for _, c := range cs {
+			indent := strings.Repeat("    ", depth)
+			header := c.Username
+			if c.Verdict != "" {
+				header += " [" + c.Verdict + "]"
+			}
+			fmt.Fprintf(&b, "%s%s:\n", indent, header)
+			for _, line := range strings.Split(c.Text, "\n") {
+				fmt.Fprintf(&b, "%s  %s\n", indent, line)
+			}
+			walk(c.Replies, depth+1)
+		}
+	}
+	walk(comments, 0)
+	return b.String()
+}
+
+// GenerateThreadSummary reads the finished thread and distills it for OP
+func GenerateThreadSummary(client *openai.Client, model string, seed *int, prompt string, comments []SimulatedComment) (*ThreadSummary, error) {
+	systemMsg := openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleSystem,
+		Content: `You are summarizing a Reddit thread for the person who wrote the original post (OP).
+Read the post and every comment and reply, then report what the thread as a whole thinks.
+Be concise: each item should be a single sentence. Speak to OP directly and kindly.`,
+	}
+
+	userMsg := openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleUser,
+		Content: fmt.Sprintf(`ORIGINAL POST:
+%s
+
+THREAD:
+%s`, prompt, threadTranscript(comments)),
+	}
+
+	list := func(desc string) map[string]any {
+		return map[string]any{
+			"type":        "array",
+			"description": desc,
+			"items":       map[string]any{"type": "string"},
+		}
+	}
+
+	fn := openai.FunctionDefinition{
+		Name:        "summarize_thread",
+		Description: "Summarize what a Reddit thread thinks about OP's post",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"themes":               list("Key themes that came up across the thread"),
+				"consensus":            list("Points most commenters agreed on"),
+				"conflicts":            list("Points where commenters disagreed with each other"),
+				"blind_spots":          list("Things the thread suggested OP may not be seeing"),
+				"reflection_questions": list("Questions OP could ask themselves"),
+			},
+			"required": []string{"themes", "consensus", "conflicts", "blind_spots", "reflection_questions"},
+		},
+	}
+
+	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:        modelOr(model, openai.GPT4),
+		Seed:         seed,
+		Messages:     []openai.ChatCompletionMessage{systemMsg, userMsg},
+		Functions:    []openai.FunctionDefinition{fn},
+		FunctionCall: openai.FunctionCall{Name: "summarize_thread"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.FunctionCall == nil {
+		return nil, fmt.Errorf("no function call in OpenAI response")
+	}
+
+	var parsed ThreadSummary
+	err = json.Unmarshal([]byte(resp.Choices[0].Message.FunctionCall.Arguments), &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal thread summary: %w", err)
+	}
+	return &parsed, nil
+}
+
+// SummaryCard renders the thread summary as a collapsible card
+func SummaryCard(s *ThreadSummary) *Node {
+	section := func(title string, items []string) *Node {
+		if len(items) == 0 {
+			return Nil()
+		}
+		list := Ul(Class("list-disc ml-6 space-y-1"))
+		for _, item := range items {
+			list.Children = append(list.Children, Li(Text(item)))
+		}
+		return Div(Class("mt-3"),
+			H3(Class("font-semibold"), Text(title)),
+			list,
+		)
+	}
+
+	return Details(Class("bg-white p-4 rounded shadow"), Open(true),
+		Summary(Class("cursor-pointer text-lg font-semibold"), T("What the thread thinks")),
+		section("Key themes", s.Themes),
+		section("Where people agreed", s.Consensus),
+		section("Where people disagreed", s.Conflicts),
+		section("Blind spots the thread raised", s.BlindSpots),
+		section("Questions to reflect on", s.ReflectionQuestions),
+	)
+}
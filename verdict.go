@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ---------- AITA VERDICTS ----------
+
+// The verdicts r/AmITheAsshole commenters are expected to lead with
+const (
+	VerdictYTA  = "YTA"
+	VerdictNTA  = "NTA"
+	VerdictESH  = "ESH"
+	VerdictNAH  = "NAH"
+	VerdictINFO = "INFO"
+)
+
+// Verdict describes a single AITA judgment and how it is displayed
+type Verdict struct {
+	Code  string
+	Label string
+	Badge string // daisyUI badge modifier
+}
+
+// All verdicts in the order the subreddit's bot lists them
+var Verdicts = []Verdict{
+	{Code: VerdictYTA, Label: "You're the Asshole", Badge: "badge-error"},
+	{Code: VerdictNTA, Label: "Not the A-hole", Badge: "badge-success"},
+	{Code: VerdictESH, Label: "Everyone Sucks Here", Badge: "badge-warning"},
+	{Code: VerdictNAH, Label: "No A-holes Here", Badge: "badge-info"},
+	{Code: VerdictINFO, Label: "Not Enough Info", Badge: "badge-neutral"},
+}
+
+// LookupVerdict returns the Verdict for a code such as "NTA"
+func LookupVerdict(code string) (Verdict, bool) {
+	for _, v := range Verdicts {
+		if v.Code == code {
+			return v, true
+		}
+	}
+	return Verdict{}, false
+}
+
+// The function-call response structure for an AITA comment
+type VerdictCommentResponse struct {
+	Verdict string `json:"verdict"`
+	Text    string `json:"text"`
+	Upvotes int    `json:"upvotes"`
+}
+
+// VerdictTally is one row of the judgment breakdown
+type VerdictTally struct {
+	Verdict Verdict
+	Weight  int
+	Percent int
+}
+
+// Judgment is the thread-wide outcome, computed the way the subreddit's bot does:
+// the top-voted verdict comment decides, and every verdict is weighted by its votes.
+type Judgment struct {
+	Winner Verdict
+	Tally  []VerdictTally
+	Total  int
+}
+
+// GenerateVerdictFromStance creates an AITA comment that leads with a structured verdict
+func GenerateVerdictFromStance(client *openai.Client, model string, seed *int, prompt string, stance Stance) (VerdictCommentResponse, error) {
+	codes := make([]string, 0, len(Verdicts))
+	for _, v := range Verdicts {
+		codes = append(codes, v.Code)
+	}
+
+	systemMsg := openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleSystem,
+		Content: fmt.Sprintf(
+			`You are a commenter on r/AmITheAsshole who holds the following stance:
+Type: %s
+SubType: %s
+Summary: %s
+
+Judge the user's post from this perspective. Pick exactly one verdict (%s),
+then write a single Reddit comment that starts with that verdict, the way
+real commenters on the subreddit do. Also estimate how many upvotes the comment
+would realistically receive.
+`,
+			stance.Type, stance.SubType, stance.Summary, strings.Join(codes, ", "),
+		),
+	}
+
+	userMsg := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: fmt.Sprintf("Here is the Reddit post:\n%s", prompt),
+	}
+
+	fn := openai.FunctionDefinition{
+		Name:        "post_verdict_comment",
+		Description: "Post a comment on r/AmITheAsshole with a verdict",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"verdict": map[string]any{"type": "string", "enum": codes},
+				"text":    map[string]any{"type": "string"},
+				"upvotes": map[string]any{"type": "integer", "minimum": 0},
+			},
+			"required": []string{"verdict", "text", "upvotes"},
+		},
+	}
+
+	resp, err := client.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model:        modelOr(model, openai.GPT4),
+			Seed:         seed,
+			Messages:     []openai.ChatCompletionMessage{systemMsg, userMsg},
+			Functions:    []openai.FunctionDefinition{fn},
+			FunctionCall: openai.FunctionCall{Name: "post_verdict_comment"},
+		},
+	)
+	if err != nil {
+		return VerdictCommentResponse{}, err
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.FunctionCall == nil {
+		return VerdictCommentResponse{}, fmt.Errorf("no function call in OpenAI response")
+	}
+
+	var parsed VerdictCommentResponse
+	err = json.Unmarshal([]byte(resp.Choices[0].Message.FunctionCall.Arguments), &parsed)
+	if err != nil {
+		return VerdictCommentResponse{}, fmt.Errorf("failed to unmarshal verdict comment: %w", err)
+	}
+	if _, ok := LookupVerdict(parsed.Verdict); !ok {
+		return VerdictCommentResponse{}, fmt.Errorf("unknown verdict %q", parsed.Verdict)
+	}
+	return parsed, nil
+}
+
+// TallyVerdicts weighs each top-level verdict by its upvotes (minimum one vote).
+// It returns false if no comment carried a verdict.
+func TallyVerdicts(comments []SimulatedComment) (Judgment, bool) {
+	weights := make(map[string]int)
+	var j Judgment
+	topScore := -1
+	for _, c := range comments {
+		v, ok := LookupVerdict(c.Verdict)
+		if !ok {
+			continue
+		}
+		weight := c.Score
+		if weight < 1 {
+			weight = 1
+		}
+		weights[v.Code] += weight
+		j.Total += weight
+		if c.Score > topScore {
+			topScore = c.Score
+			j.Winner = v
+		}
+	}
+	if j.Total == 0 {
+		return Judgment{}, false
+	}
+
+	for _, v := range Verdicts {
+		if weights[v.Code] == 0 {
+			continue
+		}
+		j.Tally = append(j.Tally, VerdictTally{
+			Verdict: v,
+			Weight:  weights[v.Code],
+			Percent: weights[v.Code] * 100 / j.Total,
+		})
+	}
+	return j, true
+}
+
+// VerdictBadge renders a small verdict pill for a comment header
+func VerdictBadge(code string) *Node {
+	v, ok := LookupVerdict(code)
+	if !ok {
+		return Nil()
+	}
+	return Span(Class("badge badge-sm "+v.Badge), Attr("title", v.Label), Text(v.Code))
+}
+
+// JudgmentBanner renders the thread's overall verdict and its weighted breakdown
+func JudgmentBanner(j Judgment) *Node {
+	breakdown := Div(Class("flex flex-wrap gap-2 mt-2"))
+	for _, t := range j.Tally {
+		breakdown.Children = append(breakdown.Children,
+			Span(Class("badge "+t.Verdict.Badge), Text(fmt.Sprintf("%s %d%%", t.Verdict.Code, t.Percent))),
+		)
+	}
+
+	return Div(Class("bg-white border-l-4 border-blue-600 p-4 rounded shadow"),
+		P(Class("text-sm text-gray-500"), T("Reddit's judgment")),
+		H2(Class("text-xl font-bold"),
+			Text(fmt.Sprintf("%s — %s", j.Winner.Code, j.Winner.Label)),
+		),
+		breakdown,
+		P(Class("text-xs text-gray-500 mt-2"),
+			Text(fmt.Sprintf("Decided by the top-voted verdict comment; breakdown weighted by %d simulated upvotes.", j.Total)),
+		),
+	)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ---------- LIVE VIEWERS ----------
+
+// Longest reply OP may post from the session page, in characters
+const maxOPReplyLength = 2000
+
+// join counts a new live stream for the presence indicator; callers hold s.mu
+func (s *RedditSession) join() {
+	s.viewers++
+	s.notify()
+}
+
+// leave undoes join; callers hold s.mu
+func (s *RedditSession) leave() {
+	s.viewers--
+	s.notify()
+}
+
+// presenceEvent tells a viewer how many people are watching. It carries the seq
+// of the last logged event the viewer got, since presence itself isn't logged.
+func presenceEvent(viewers, seq int) SessionEvent {
+	return SessionEvent{V: ProtocolVersion, Seq: seq, Type: EventPresence, Viewers: viewers}
+}
+
+// AddOPReply posts text as OP's reply to a top-level comment. Everyone watching
+// the session gets it as a reply event, like any generated reply.
+func AddOPReply(s *RedditSession, id, text string) error {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "c"))
+	if err != nil || commentID(n) != id {
+		return errors.New("no such comment")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deleted || n < 0 || n >= len(s.Responses) {
+		return errors.New("no such comment")
+	}
+	s.Responses[n].Replies = append(s.Responses[n].Replies, SimulatedComment{
+		Username: "OP",
+		Flair:    "OP",
+		Text:     text,
+	})
+	s.publish()
+	return nil
+}
+
+// OPReplyForm is copied under each top-level comment by the session page script
+func OPReplyForm() *Node {
+	return Details(Id("opReplyTemplate"), Class("op-reply hidden ml-6 mb-4"),
+		Summary(Class("cursor-pointer text-sm text-blue-700"), T("Reply as OP")),
+		Form(Class("mt-2 space-y-2"), Attr("onsubmit", "return replyAsOP(this)"),
+			TextArea(Name("text"), Rows(3), Attr("maxlength", strconv.Itoa(maxOPReplyLength)), Required(true),
+				Class("w-full border rounded p-2")),
+			Button(Type("submit"), Class("btn btn-sm btn-primary"), T("Reply")),
+		),
+	)
+}
+
+// opReplyHandler serves POST /reply?id=...&comment=c2, used by the Reply as OP forms
+func opReplyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session, ok := authorizeSession(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+	text := strings.TrimSpace(r.FormValue("text"))
+	if text == "" {
+		http.Error(w, "Reply text is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(text) > maxOPReplyLength {
+		http.Error(w, "Reply is too long", http.StatusBadRequest)
+		return
+	}
+	if err := AddOPReply(session, r.URL.Query().Get("comment"), text); err != nil {
+		http.Error(w, "No such comment", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}